@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/richard-on/task-service/config"
+	"github.com/richard-on/task-service/internal/db"
+	encrypt "github.com/richard-on/task-service/internal/encrypt"
+	"github.com/richard-on/task-service/pkg/logger"
+	"strconv"
+)
+
+const usage = `usage: task [command]
+
+Without a command the HTTP server is started.
+
+commands:
+  verify <task_id>  verify hash chain of the task audit trail
+  encrypt           encrypt sensitive fields of tasks stored in plaintext and
+                    re-wrap data keys with the primary key before older keys are retired
+  migrate [up]      apply all pending database migrations
+  migrate down <n>  revert migrations newer than schema version n
+  migrate version   print the current and the latest schema version`
+
+// runCommand runs the CLI subcommand given in args and returns the process exit code.
+func runCommand(log logger.Logger, args []string) int {
+	switch args[0] {
+	case "verify":
+		if len(args) != 2 {
+			fmt.Println(usage)
+			return 2
+		}
+		return verify(log, args[1])
+
+	case "encrypt":
+		return encryptTasks(log)
+
+	case "migrate":
+		switch {
+		case len(args) == 1 || len(args) == 2 && args[1] == "up":
+			return migrate(log, func(ctx context.Context, m db.Migrator) error {
+				return m.Migrate(ctx)
+			})
+		case len(args) == 2 && args[1] == "version":
+			return migrate(log, func(ctx context.Context, m db.Migrator) error {
+				version, err := m.SchemaVersion(ctx)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("schema version %v, latest %v\n", version, m.LatestVersion())
+
+				return nil
+			})
+		case len(args) == 3 && args[1] == "down":
+			version, err := strconv.Atoi(args[2])
+			if err != nil {
+				fmt.Println(usage)
+				return 2
+			}
+			return migrate(log, func(ctx context.Context, m db.Migrator) error {
+				return m.MigrateTo(ctx, version)
+			})
+		}
+		fmt.Println(usage)
+		return 2
+
+	default:
+		fmt.Println(usage)
+		return 2
+	}
+}
+
+// verify walks the audit trail of the task and reports the first broken link.
+func verify(log logger.Logger, taskID string) int {
+	envelope, err := envelopeFromConfig()
+	if err != nil {
+		log.Error(err, "failed to load encryption keys")
+		return 1
+	}
+
+	store, closeDb, err := db.Open(context.Background(), envelope)
+	if err != nil {
+		log.Error(err, "failed to connect to database")
+		return 1
+	}
+	defer func() {
+		if err = closeDb(); err != nil {
+			log.Error(err, "failed to disconnect db")
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.DbTimeout)
+	defer cancel()
+
+	task, err := store.GetTaskById(ctx, taskID)
+	if err != nil {
+		log.Error(err, "unable to get task")
+		return 1
+	}
+
+	broken, err := task.VerifyHistory()
+	if err != nil {
+		log.Error(err, "unable to verify task history")
+		return 1
+	}
+
+	if broken >= 0 {
+		e := task.History[broken]
+		fmt.Printf("task %v: history is broken at event %v of %v (%v by %v at %v)\n",
+			taskID, broken, len(task.History), e.Action, e.Actor, e.Time)
+		return 1
+	}
+
+	fmt.Printf("task %v: history is intact, %v events verified\n", taskID, len(task.History))
+
+	return 0
+}
+
+// encryptTasks encrypts sensitive fields of all tasks stored before encryption at rest was enabled
+// and re-wraps data keys wrapped with keys other than the primary one.
+func encryptTasks(log logger.Logger) int {
+	envelope, err := envelopeFromConfig()
+	if err != nil {
+		log.Error(err, "failed to load encryption keys")
+		return 1
+	} else if envelope == nil {
+		log.Error(db.ErrNoEnvelope, "ENCRYPT_KEYS is not set")
+		return 1
+	}
+
+	store, closeDb, err := db.Open(context.Background(), envelope)
+	if err != nil {
+		log.Error(err, "failed to connect to database")
+		return 1
+	}
+	defer func() {
+		if err = closeDb(); err != nil {
+			log.Error(err, "failed to disconnect db")
+		}
+	}()
+
+	encrypter, ok := store.(db.Encrypter)
+	if !ok {
+		log.Infof("%v backend does not support encryption of existing tasks", config.DbBackend)
+		return 1
+	}
+
+	// Encryption runs through all stored tasks, so it is not limited by DB_TIMEOUT.
+	n, err := encrypter.EncryptExisting(context.Background())
+	if err != nil {
+		log.Errorf(err, "encryption stopped after %v tasks", n)
+		return 1
+	}
+
+	fmt.Printf("encrypted %v tasks\n", n)
+
+	n, err = encrypter.RewrapExisting(context.Background())
+	if err != nil {
+		log.Errorf(err, "re-wrapping stopped after %v tasks", n)
+		return 1
+	}
+
+	fmt.Printf("re-wrapped data keys of %v tasks\n", n)
+
+	return 0
+}
+
+// migrate runs fn against the configured store if it has a versioned schema.
+func migrate(log logger.Logger, fn func(ctx context.Context, m db.Migrator) error) int {
+	store, closeDb, err := db.Open(context.Background(), nil)
+	if err != nil {
+		log.Error(err, "failed to connect to database")
+		return 1
+	}
+	defer func() {
+		if err = closeDb(); err != nil {
+			log.Error(err, "failed to disconnect db")
+		}
+	}()
+
+	migrator, ok := store.(db.Migrator)
+	if !ok {
+		log.Infof("%v backend has no schema to migrate", config.DbBackend)
+		return 0
+	}
+
+	if err = fn(context.Background(), migrator); err != nil {
+		log.Error(err, "migration failed")
+		return 1
+	}
+
+	return 0
+}
+
+// envelopeFromConfig returns an Envelope built from ENCRYPT_KEYS or nil if no keys are configured.
+// Unlike the server, commands always use the keys to read tasks encrypted at rest.
+func envelopeFromConfig() (*encrypt.Envelope, error) {
+	if config.EncryptKeys == "" {
+		return nil, nil
+	}
+
+	keyring, err := encrypt.ParseKeyring(config.EncryptKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return encrypt.NewEnvelope(keyring), nil
+}
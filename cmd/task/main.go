@@ -70,6 +70,12 @@ func main() {
 		log.Info("sentry setup complete")
 	}
 
+	if len(os.Args) > 1 {
+		code := runCommand(log, os.Args[1:])
+		sentry.Flush(2 * time.Second)
+		os.Exit(code)
+	}
+
 	// Start Fiber server
 	server := server.NewApp()
 	server.Run()
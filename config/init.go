@@ -3,8 +3,11 @@ package config
 import (
 	"fmt"
 	"github.com/richard-on/task-service/pkg/logger"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
 )
@@ -30,6 +33,34 @@ var DbInfo struct {
 }
 var DbConnString string
 
+var DbBackend string
+var PostgresConnString string
+var BoltPath string
+var DbTimeout time.Duration
+var DbMigrate bool
+
+var SigningKey string
+
+var EncryptKeys string
+var EncryptAtRest bool
+
+var ActionInfo struct {
+	TTL time.Duration
+}
+
+var PublicURL string
+
+var MailToken string
+
+// Admins are emails of users allowed to purge tasks.
+var Admins []string
+
+// DeclineReasonRequired makes coordinators give a reason when they decline a task.
+var DeclineReasonRequired bool
+
+// WorkerInterval is the interval between runs of the worker. Zero disables the worker.
+var WorkerInterval time.Duration
+
 var MongoDbName string
 var MongoCollection string
 
@@ -88,6 +119,91 @@ func Init(log logger.Logger) {
 	DbConnString = fmt.Sprintf("%s://%s:%s/",
 		DbInfo.Name, DbInfo.Host, DbInfo.Port)
 
+	DbBackend = os.Getenv("DB_BACKEND")
+	if DbBackend == "" && Env == "dev" {
+		// Local development runs without external databases.
+		DbBackend = "bolt"
+	}
+
+	DbTimeout, err = time.ParseDuration(os.Getenv("DB_TIMEOUT"))
+	if err != nil {
+		log.Infof("DB_TIMEOUT init: %v", err)
+		DbTimeout = 5 * time.Second
+	}
+
+	DbMigrate = true
+	if v := os.Getenv("DB_MIGRATE"); v != "" {
+		DbMigrate, err = strconv.ParseBool(v)
+		if err != nil {
+			log.Infof("DB_MIGRATE init: %v", err)
+			DbMigrate = true
+		}
+	}
+
+	BoltPath = os.Getenv("BOLT_PATH")
+	if BoltPath == "" {
+		BoltPath = "data/task.db"
+	}
+
+	sslMode := os.Getenv("POSTGRES_SSLMODE")
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	PostgresConnString = (&url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(DbInfo.User, DbInfo.Password),
+		Host:     DbInfo.Host + ":" + DbInfo.Port,
+		Path:     os.Getenv("POSTGRES_DB"),
+		RawQuery: url.Values{"sslmode": {sslMode}}.Encode(),
+	}).String()
+
+	SigningKey = os.Getenv("SIGNING_KEY")
+
+	EncryptKeys = os.Getenv("ENCRYPT_KEYS")
+	if EncryptKeys == "" && os.Getenv("ACTION_KEY") != "" {
+		// ACTION_KEY predates key rotation and is used as the only key.
+		EncryptKeys = "action:" + os.Getenv("ACTION_KEY")
+	}
+
+	EncryptAtRest, err = strconv.ParseBool(os.Getenv("ENCRYPT_AT_REST"))
+	if err != nil {
+		log.Infof("ENCRYPT_AT_REST init: %v", err)
+	}
+
+	ActionInfo.TTL, err = time.ParseDuration(os.Getenv("ACTION_TTL"))
+	if err != nil {
+		log.Infof("ACTION_TTL init: %v", err)
+		ActionInfo.TTL = 72 * time.Hour
+	}
+
+	MailToken = os.Getenv("MAIL_TOKEN")
+
+	Admins = nil
+	for _, email := range strings.Split(os.Getenv("ADMINS"), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			Admins = append(Admins, email)
+		}
+	}
+
+	DeclineReasonRequired, err = strconv.ParseBool(os.Getenv("DECLINE_REASON_REQUIRED"))
+	if err != nil {
+		log.Infof("DECLINE_REASON_REQUIRED init: %v", err)
+	}
+
+	WorkerInterval, err = time.ParseDuration(os.Getenv("WORKER_INTERVAL"))
+	if err != nil {
+		log.Infof("WORKER_INTERVAL init: %v", err)
+		WorkerInterval = time.Minute
+	} else if WorkerInterval < 0 {
+		log.Infof("WORKER_INTERVAL init: negative interval %v", WorkerInterval)
+		WorkerInterval = time.Minute
+	}
+
+	PublicURL = os.Getenv("PUBLIC_URL")
+	if PublicURL == "" {
+		PublicURL = "http://localhost:5000"
+	}
+
 	MongoDbName = os.Getenv("MONGO_DB")
 	MongoCollection = os.Getenv("MONGO_COLLECTION")
 }
@@ -9,10 +9,13 @@ require (
 	github.com/gofiber/swagger v0.1.8
 	github.com/google/uuid v1.3.0
 	github.com/joho/godotenv v1.4.0
+	github.com/lib/pq v1.10.7
 	github.com/matcornic/hermes/v2 v2.1.0
 	github.com/richard-on/auth-service v0.1.0
+	github.com/richard-on/mail-service v0.0.0-20221207183411-79f788a189fb
 	github.com/rs/zerolog v1.28.0
 	github.com/valyala/fasthttp v1.43.0
+	go.etcd.io/bbolt v1.3.6
 	go.mongodb.org/mongo-driver v1.11.0
 	google.golang.org/grpc v1.51.0
 )
@@ -57,7 +60,6 @@ require (
 	github.com/prometheus/client_model v0.3.0 // indirect
 	github.com/prometheus/common v0.37.0 // indirect
 	github.com/prometheus/procfs v0.8.0 // indirect
-	github.com/richard-on/mail-service v0.0.0-20221207183411-79f788a189fb // indirect
 	github.com/rivo/uniseg v0.4.3 // indirect
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/ssor/bom v0.0.0-20170718123548-6386211fdfcf // indirect
@@ -0,0 +1,373 @@
+package db
+
+import (
+	"context"
+	"github.com/richard-on/task-service/config"
+	encrypt "github.com/richard-on/task-service/internal/encrypt"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/logger"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var _ Store = (*Bolt)(nil)
+var _ Encrypter = (*Bolt)(nil)
+
+var (
+	tasksBucket       = []byte("tasks")
+	delegationsBucket = []byte("delegations")
+)
+
+// Bolt is a Store kept in a single file with an embedded bbolt database, meant for
+// single-node deployments and local development. Tasks and delegations are stored
+// as BSON documents keyed by ID, each in their own bucket.
+// If Envelope is set, sensitive task fields are encrypted at rest.
+type Bolt struct {
+	Db       *bolt.DB
+	Log      logger.Logger
+	Envelope *encrypt.Envelope
+}
+
+// OpenBolt opens or creates the database file at path.
+func OpenBolt(path string) (*Bolt, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{tasksBucket, delegationsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Bolt{
+		Db: db,
+		Log: logger.NewLogger(
+			config.DefaultWriter,
+			config.LogInfo.Level,
+			"task-bolt"),
+	}, nil
+}
+
+func (b *Bolt) AddTask(ctx context.Context, task model.Task) (model.Task, error) {
+	if task.ID.IsZero() {
+		task.ID = primitive.NewObjectID()
+	}
+	created(&task)
+
+	sealed, err := seal(b.Envelope, task)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	raw, err := bson.Marshal(sealed)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	err = b.update(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).Put([]byte(sealed.ID.Hex()), raw)
+	})
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	task.DataKey = sealed.DataKey
+	task.Saved()
+
+	return task, nil
+}
+
+func (b *Bolt) ListTasks(ctx context.Context, query Query) (Page, error) {
+	tasks, err := b.scan(ctx, query.match)
+	if err != nil {
+		return Page{}, err
+	}
+
+	return query.pageOf(tasks)
+}
+
+func (b *Bolt) SearchTasks(ctx context.Context, email, text string, limit int) ([]SearchResult, error) {
+	tasks, err := b.scan(ctx, func(task model.Task) bool { return task.Participant(email) })
+	if err != nil {
+		return nil, err
+	}
+
+	return rank(tasks, SearchTerms(text), limit), nil
+}
+
+func (b *Bolt) GetAwaitingTasks(ctx context.Context, email string) ([]model.Task, error) {
+	return b.scan(ctx, func(task model.Task) bool { return task.CanAct(email) })
+}
+
+func (b *Bolt) GetDecidedTasks(ctx context.Context, email string) ([]model.Task, error) {
+	return b.scan(ctx, func(task model.Task) bool { return task.Decided(email) })
+}
+
+// scan returns all stored tasks for which match returns true. Tasks are not indexed,
+// which is fine for the amount of tasks a single node deployment holds.
+func (b *Bolt) scan(ctx context.Context, match func(task model.Task) bool) ([]model.Task, error) {
+	var tasks []model.Task
+
+	err := b.view(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).ForEach(func(_, raw []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			task, err := b.decode(raw)
+			if err != nil {
+				return err
+			}
+			if match(task) {
+				tasks = append(tasks, task)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+func (b *Bolt) GetOverdueTasks(ctx context.Context, now time.Time) ([]model.Task, error) {
+	return b.scan(ctx, func(task model.Task) bool { return task.Overdue(now) })
+}
+
+func (b *Bolt) GetBreachedTasks(ctx context.Context, now time.Time) ([]model.Task, error) {
+	return b.scan(ctx, func(task model.Task) bool { return task.Breached(now) })
+}
+
+func (b *Bolt) GetTaskById(ctx context.Context, taskId string) (model.Task, error) {
+	if _, err := primitive.ObjectIDFromHex(taskId); err != nil {
+		return model.Task{}, err
+	}
+
+	var task model.Task
+	err := b.view(ctx, func(tx *bolt.Tx) error {
+		raw := tx.Bucket(tasksBucket).Get([]byte(taskId))
+		if raw == nil {
+			return ErrNotFound
+		}
+
+		var err error
+		task, err = b.decode(raw)
+		return err
+	})
+
+	return task, err
+}
+
+// UpdateTask stores task within a single read-write transaction,
+// so the version check and the write can't be interleaved with other updates.
+func (b *Bolt) UpdateTask(ctx context.Context, task *model.Task) error {
+	sealed, err := seal(b.Envelope, *task)
+	if err != nil {
+		return err
+	}
+	sealed.UpdatedAt = now()
+
+	err = b.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(tasksBucket)
+		key := []byte(task.ID.Hex())
+
+		current := bucket.Get(key)
+		if current == nil {
+			return ErrConflict
+		}
+
+		var stored model.Task
+		if err := bson.Unmarshal(current, &stored); err != nil {
+			return err
+		}
+		if stored.Version != task.Version {
+			return ErrConflict
+		}
+
+		sealed.History = append(stored.History, sealed.Recorded()...)
+		sealed.Version++
+
+		raw, err := bson.Marshal(sealed)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(key, raw)
+	})
+	if err != nil {
+		return err
+	}
+
+	task.Version++
+	task.UpdatedAt = sealed.UpdatedAt
+	task.DataKey = sealed.DataKey
+	task.Saved()
+
+	return nil
+}
+
+func (b *Bolt) DeleteTask(ctx context.Context, taskId string) error {
+	if _, err := primitive.ObjectIDFromHex(taskId); err != nil {
+		return err
+	}
+
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(tasksBucket)
+		if bucket.Get([]byte(taskId)) == nil {
+			return ErrNotFound
+		}
+
+		return bucket.Delete([]byte(taskId))
+	})
+}
+
+func (b *Bolt) AddDelegation(ctx context.Context, delegation model.Delegation) (model.Delegation, error) {
+	if delegation.ID.IsZero() {
+		delegation.ID = primitive.NewObjectID()
+	}
+	delegation.CreatedAt = now()
+
+	raw, err := bson.Marshal(delegation)
+	if err != nil {
+		return model.Delegation{}, err
+	}
+
+	err = b.update(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket(delegationsBucket).Put([]byte(delegation.ID.Hex()), raw)
+	})
+	if err != nil {
+		return model.Delegation{}, err
+	}
+
+	return delegation, nil
+}
+
+func (b *Bolt) GetDelegations(ctx context.Context, email string) ([]model.Delegation, error) {
+	var delegations []model.Delegation
+
+	err := b.view(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket(delegationsBucket).ForEach(func(_, raw []byte) error {
+			var d model.Delegation
+			if err := bson.Unmarshal(raw, &d); err != nil {
+				return err
+			}
+			if d.Delegator == email || d.Delegate == email {
+				delegations = append(delegations, d)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortDelegations(delegations)
+
+	return delegations, nil
+}
+
+func (b *Bolt) DeleteDelegation(ctx context.Context, delegationId, delegator string) error {
+	if _, err := primitive.ObjectIDFromHex(delegationId); err != nil {
+		return err
+	}
+
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(delegationsBucket)
+
+		raw := bucket.Get([]byte(delegationId))
+		if raw == nil {
+			return ErrDelegationNotFound
+		}
+
+		var d model.Delegation
+		if err := bson.Unmarshal(raw, &d); err != nil {
+			return err
+		}
+		if d.Delegator != delegator {
+			return ErrDelegationNotFound
+		}
+
+		return bucket.Delete([]byte(delegationId))
+	})
+}
+
+func (b *Bolt) EncryptExisting(ctx context.Context) (int, error) {
+	return encryptExisting(ctx, b, b.Envelope)
+}
+
+func (b *Bolt) RewrapExisting(ctx context.Context) (int, error) {
+	return rewrapExisting(ctx, b, b.Envelope)
+}
+
+func (b *Bolt) storedKeys(ctx context.Context) ([]storedKey, error) {
+	var keys []storedKey
+	err := b.view(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).ForEach(func(_, raw []byte) error {
+			var key storedKey
+			if err := bson.Unmarshal(raw, &key); err != nil {
+				return err
+			}
+			keys = append(keys, key)
+
+			return nil
+		})
+	})
+
+	return keys, err
+}
+
+// Close closes the database file.
+func (b *Bolt) Close() error {
+	return b.Db.Close()
+}
+
+// view runs fn in a read-only transaction unless ctx is already done.
+// bbolt transactions can't be interrupted, so ctx is only checked before fn starts.
+func (b *Bolt) view(ctx context.Context, fn func(tx *bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.Db.View(fn)
+}
+
+// update runs fn in a read-write transaction unless ctx is already done.
+func (b *Bolt) update(ctx context.Context, fn func(tx *bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.Db.Update(fn)
+}
+
+// decode unmarshals a stored task and decrypts its sensitive fields.
+func (b *Bolt) decode(raw []byte) (model.Task, error) {
+	var task model.Task
+	if err := bson.Unmarshal(raw, &task); err != nil {
+		return model.Task{}, err
+	}
+	if err := open(b.Envelope, &task); err != nil {
+		return model.Task{}, err
+	}
+
+	return task, nil
+}
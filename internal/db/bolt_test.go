@@ -0,0 +1,246 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	encrypt "github.com/richard-on/task-service/internal/encrypt"
+	"github.com/richard-on/task-service/internal/model"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestBolt(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data", "tasks.db")
+	store, err := OpenBolt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := model.Task{Name: "Contract", Initiator: "initiator", Status: model.NotStarted}
+	if err = task.Record(model.Event{Action: model.ActionCreated}); err != nil {
+		t.Fatal(err)
+	}
+	task, err = store.AddTask(ctx, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := task.ID.Hex()
+
+	first, err := store.GetTaskById(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := store.GetTaskById(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first.Description = "Draft"
+	if err = first.Record(model.Event{Action: model.ActionApproved}); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.UpdateTask(ctx, &first); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.UpdateTask(ctx, &second); err != ErrConflict {
+		t.Fatalf("update of a stale copy: err = %v, want %v", err, ErrConflict)
+	}
+
+	// Tasks survive reopening the file.
+	if err = store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if store, err = OpenBolt(path); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	page, err := store.ListTasks(ctx, Query{Initiator: "initiator"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks := page.Tasks
+	if len(tasks) != 1 || tasks[0].Description != "Draft" || tasks[0].Version != 1 || len(tasks[0].History) != 2 {
+		t.Fatalf("tasks = %+v, want the updated task", tasks)
+	}
+	if broken, err := tasks[0].VerifyHistory(); err != nil || broken != -1 {
+		t.Errorf("VerifyHistory() = %v, %v, want an intact chain", broken, err)
+	}
+	if page, err = store.ListTasks(ctx, Query{Initiator: "coordinator"}); err != nil || len(page.Tasks) != 0 {
+		t.Errorf("tasks of another user = %+v, %v", page.Tasks, err)
+	}
+
+	if err = store.DeleteTask(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.GetTaskById(ctx, id); err != ErrNotFound {
+		t.Errorf("get of a deleted task: err = %v, want %v", err, ErrNotFound)
+	}
+	if err = store.DeleteTask(ctx, id); err != ErrNotFound {
+		t.Errorf("delete of a deleted task: err = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestBoltEncryptExisting(t *testing.T) {
+	ctx := context.Background()
+	store, err := OpenBolt(filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	plain, err := store.AddTask(ctx, model.Task{Name: "Contract", Description: "Draft", Status: model.NotStarted})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyring, err := encrypt.NewKeyring(encrypt.Key{ID: "v1", Secret: bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Envelope = encrypt.NewEnvelope(keyring)
+
+	// Tasks stored before encryption was enabled are still readable.
+	if task, err := store.GetTaskById(ctx, plain.ID.Hex()); err != nil || task.Name != "Contract" {
+		t.Fatalf("GetTaskById() = %+v, %v", task, err)
+	}
+
+	if n, err := store.EncryptExisting(ctx); err != nil || n != 1 {
+		t.Fatalf("EncryptExisting() = %v, %v, want 1", n, err)
+	}
+	if n, err := store.EncryptExisting(ctx); err != nil || n != 0 {
+		t.Errorf("EncryptExisting() again = %v, %v, want 0", n, err)
+	}
+
+	var stored model.Task
+	err = store.Db.View(func(tx *bolt.Tx) error {
+		return bson.Unmarshal(tx.Bucket(tasksBucket).Get([]byte(plain.ID.Hex())), &stored)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.DataKey == "" || stored.Name == "Contract" || stored.Description == "Draft" {
+		t.Errorf("stored %+v, want sensitive fields encrypted", stored)
+	}
+
+	task, err := store.GetTaskById(ctx, plain.ID.Hex())
+	if err != nil || task.Name != "Contract" || task.Description != "Draft" {
+		t.Errorf("GetTaskById() = %+v, %v, want decrypted fields", task, err)
+	}
+}
+
+func TestBoltEncryptComments(t *testing.T) {
+	ctx := context.Background()
+	store, err := OpenBolt(filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	keyring, err := encrypt.NewKeyring(encrypt.Key{ID: "v1", Secret: bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Envelope = encrypt.NewEnvelope(keyring)
+
+	task, err := store.AddTask(ctx, model.Task{Name: "Contract", Status: model.InProgress,
+		Stages: []model.Stage{{Coordinators: []string{"a"}, Mode: model.Sequential}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = task.Decline("a", "missing signature"); err != nil {
+		t.Fatal(err)
+	}
+	if err = task.Record(model.Event{Action: model.ActionDeclined, Actor: "a", Comment: "missing signature"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.UpdateTask(ctx, &task); err != nil {
+		t.Fatal(err)
+	}
+	if comment := task.Stages[0].Decisions[0].Comment; comment != "missing signature" {
+		t.Errorf("UpdateTask() changed the caller's comment to %q", comment)
+	}
+
+	var stored model.Task
+	err = store.Db.View(func(tx *bolt.Tx) error {
+		return bson.Unmarshal(tx.Bucket(tasksBucket).Get([]byte(task.ID.Hex())), &stored)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Stages[0].Decisions[0].Comment == "missing signature" || stored.History[0].Comment == "missing signature" {
+		t.Errorf("stored %+v, want comments encrypted", stored)
+	}
+
+	got, err := store.GetTaskById(ctx, task.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Stages[0].Decisions[0].Comment != "missing signature" || got.History[0].Comment != "missing signature" {
+		t.Errorf("GetTaskById() = %+v, want decrypted comments", got)
+	}
+	if broken, err := got.VerifyHistory(); err != nil || broken != -1 {
+		t.Errorf("VerifyHistory() = %v, %v, want an intact chain", broken, err)
+	}
+}
+
+func TestBoltRewrapExisting(t *testing.T) {
+	ctx := context.Background()
+	store, err := OpenBolt(filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	old := encrypt.Key{ID: "v1", Secret: bytes.Repeat([]byte{1}, 32)}
+	keyring, err := encrypt.NewKeyring(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Envelope = encrypt.NewEnvelope(keyring)
+
+	task, err := store.AddTask(ctx, model.Task{Name: "Contract", Status: model.NotStarted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := store.RewrapExisting(ctx); err != nil || n != 0 {
+		t.Fatalf("RewrapExisting() before rotation = %v, %v, want 0", n, err)
+	}
+
+	// Once v2 becomes the primary key, v1 can only be retired after every data key is re-wrapped.
+	keyring, err = encrypt.NewKeyring(old, encrypt.Key{ID: "v2", Secret: bytes.Repeat([]byte{2}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Envelope = encrypt.NewEnvelope(keyring)
+
+	if n, err := store.RewrapExisting(ctx); err != nil || n != 1 {
+		t.Fatalf("RewrapExisting() = %v, %v, want 1", n, err)
+	}
+	if n, err := store.RewrapExisting(ctx); err != nil || n != 0 {
+		t.Errorf("RewrapExisting() again = %v, %v, want 0", n, err)
+	}
+	if n, err := store.EncryptExisting(ctx); err != nil || n != 0 {
+		t.Errorf("EncryptExisting() of encrypted tasks = %v, %v, want 0", n, err)
+	}
+
+	var stored model.Task
+	err = store.Db.View(func(tx *bolt.Tx) error {
+		return bson.Unmarshal(tx.Bucket(tasksBucket).Get([]byte(task.ID.Hex())), &stored)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := encrypt.KeyID(stored.DataKey); id != "v2" {
+		t.Errorf("stored data key wrapped with %q, want v2", id)
+	}
+
+	got, err := store.GetTaskById(ctx, task.ID.Hex())
+	if err != nil || got.Name != "Contract" {
+		t.Errorf("GetTaskById() = %+v, %v, want decrypted fields", got, err)
+	}
+}
@@ -2,24 +2,41 @@ package db
 
 import (
 	"context"
+	"errors"
 	"github.com/richard-on/task-service/config"
+	encrypt "github.com/richard-on/task-service/internal/encrypt"
 	"github.com/richard-on/task-service/internal/model"
 	"github.com/richard-on/task-service/pkg/logger"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+	"time"
 )
 
+// ErrConflict is returned when a task was modified by someone else since it was read.
+var ErrConflict = errors.New("task has been modified concurrently")
+
+// ErrNoEnvelope is returned when a task is encrypted at rest, but no encryption keys are configured.
+var ErrNoEnvelope = errors.New("encryption at rest is not configured")
+
+var _ Store = (*DB)(nil)
+var _ Encrypter = (*DB)(nil)
+
+// delegationCollection holds delegations. It lives in the same database as the task collection.
+const delegationCollection = "delegations"
+
+// DB is a Store backed by MongoDB. If Envelope is set, sensitive task fields are encrypted at rest.
 type DB struct {
-	Db  *mongo.Collection
-	Ctx context.Context
-	Log logger.Logger
+	Db       *mongo.Collection
+	Log      logger.Logger
+	Envelope *encrypt.Envelope
 }
 
-func NewDatabase(ctx context.Context, db *mongo.Collection) *DB {
+func NewDatabase(db *mongo.Collection) *DB {
 	return &DB{
-		Db:  db,
-		Ctx: ctx,
+		Db: db,
 		Log: logger.NewLogger(
 			config.DefaultWriter,
 			config.LogInfo.Level,
@@ -27,79 +44,393 @@ func NewDatabase(ctx context.Context, db *mongo.Collection) *DB {
 	}
 }
 
-func (db *DB) AddTask(task model.Task) (model.Task, error) {
-	res, err := db.Db.InsertOne(db.Ctx, task)
+// Connect connects to MongoDB described by config and returns the client along with the task collection.
+func Connect(ctx context.Context) (*mongo.Client, *mongo.Collection, error) {
+	client, err := mongo.Connect(ctx,
+		options.Client().ApplyURI(config.DbConnString))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return client, client.Database(config.MongoDbName).Collection(config.MongoCollection), nil
+}
+
+func (db *DB) AddTask(ctx context.Context, task model.Task) (model.Task, error) {
+	created(&task)
+
+	sealed, err := seal(db.Envelope, task)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	res, err := db.Db.InsertOne(ctx, sealed)
 	if err != nil {
 		return model.Task{}, err
 	}
+	task.DataKey = sealed.DataKey
 
 	db.Log.Debug(res.InsertedID.(primitive.ObjectID).String())
+	task.Saved()
 
 	return task, nil
 }
 
-func (db *DB) GetAllTasks(email string) ([]model.Task, error) {
-	cursor, err := db.Db.Find(db.Ctx, bson.M{"initiator": email})
+// ListTasks filters, sorts and pages tasks in MongoDB. Pages are selected by the sort value
+// and ID of the last task of the previous page, so they stay stable while tasks are added.
+func (db *DB) ListTasks(ctx context.Context, query Query) (Page, error) {
+	if err := query.validate(); err != nil {
+		return Page{}, err
+	}
+	if db.Envelope != nil && query.usesName() {
+		return Page{}, ErrEncryptedField
+	}
+
+	after, err := query.cursor()
+	if err != nil {
+		return Page{}, err
+	}
+
+	filter := query.mongoFilter()
+	total, err := db.Db.CountDocuments(ctx, filter)
+	if err != nil {
+		return Page{}, err
+	}
+
+	if after != nil {
+		filter = bson.M{"$and": bson.A{filter, query.mongoAfter(after)}}
+	}
+
+	order := 1
+	if query.Desc {
+		order = -1
+	}
+	tasks, err := db.find(ctx, filter, nil, options.Find().
+		SetSort(bson.D{{Key: string(query.sortField()), Value: order}, {Key: "_id", Value: order}}).
+		SetLimit(int64(query.limit()+1)))
+	if err != nil {
+		return Page{}, err
+	}
+
+	page, next := query.nextCursor(tasks)
+
+	return Page{Tasks: page, NextCursor: next, Total: total}, nil
+}
+
+// SearchTasks uses the text index of the task collection. When encryption at rest is enabled,
+// the index holds no words, so tasks visible to email are ranked in the service instead.
+func (db *DB) SearchTasks(ctx context.Context, email, text string, limit int) ([]SearchResult, error) {
+	visible := bson.M{"$or": bson.A{bson.M{"initiator": email}, bson.M{"coordinators": email}}}
+
+	if db.Envelope != nil {
+		tasks, err := db.find(ctx, visible, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return rank(tasks, SearchTerms(text), limit), nil
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	cursor, err := db.Db.Find(ctx,
+		bson.M{"$and": bson.A{visible, bson.M{"$text": bson.M{"$search": text}}}},
+		options.Find().
+			SetProjection(bson.M{"score": score}).
+			SetSort(bson.M{"score": score}).
+			SetLimit(int64(searchLimit(limit))))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []SearchResult
+	for cursor.Next(ctx) {
+		var hit struct {
+			model.Task `bson:",inline"`
+			Score      float64 `bson:"score"`
+		}
+		if err = cursor.Decode(&hit); err != nil {
+			return nil, err
+		}
+		results = append(results, SearchResult{Task: hit.Task, Score: hit.Score})
+	}
+
+	return results, cursor.Err()
+}
+
+// GetAwaitingTasks looks unfinished tasks up by the status and coordinators index. Whether the coordinator
+// may act depends on stage configuration and is checked after the tasks are read.
+func (db *DB) GetAwaitingTasks(ctx context.Context, email string) ([]model.Task, error) {
+	filter := bson.M{
+		"status":       bson.M{"$in": bson.A{model.NotStarted, model.InProgress}},
+		"coordinators": email,
+	}
+
+	return db.find(ctx, filter, func(task model.Task) bool {
+		return task.CanAct(email)
+	})
+}
+
+func (db *DB) GetDecidedTasks(ctx context.Context, email string) ([]model.Task, error) {
+	return db.find(ctx, bson.M{"$or": bson.A{
+		bson.M{"stages.decisions.coordinator": email},
+		bson.M{"stages.decisions.decided_by": email},
+		bson.M{"revisions.stages.decisions.coordinator": email},
+		bson.M{"revisions.stages.decisions.decided_by": email},
+	}}, nil)
+}
+
+// find returns tasks matching filter for which match returns true. Nil match accepts every task.
+func (db *DB) find(ctx context.Context, filter bson.M, match func(task model.Task) bool,
+	opts ...*options.FindOptions) ([]model.Task, error) {
+	cursor, err := db.Db.Find(ctx, filter, opts...)
 	if err != nil {
 		return nil, err
 	}
+	defer cursor.Close(ctx)
 
 	var tasks []model.Task
-	for cursor.Next(db.Ctx) {
+	for cursor.Next(ctx) {
 		var task model.Task
 		if err = cursor.Decode(&task); err != nil {
 			return nil, err
 		}
+		if match != nil && !match(task) {
+			continue
+		}
+		if err = open(db.Envelope, &task); err != nil {
+			return nil, err
+		}
 		db.Log.Debug(task.Initiator)
 		tasks = append(tasks, task)
 	}
 
-	return tasks, nil
+	return tasks, cursor.Err()
+}
+
+func (db *DB) GetOverdueTasks(ctx context.Context, now time.Time) ([]model.Task, error) {
+	return db.find(ctx, bson.M{
+		"due_at": bson.M{"$lte": now},
+		"status": bson.M{"$in": bson.A{model.NotStarted, model.InProgress}},
+	}, func(task model.Task) bool {
+		return task.Overdue(now)
+	})
+}
+
+// GetBreachedTasks selects unfinished tasks with an SLA on any stage. Whether a coordinator of the current
+// stage is past its SLA depends on the task history and is checked after the tasks are read.
+func (db *DB) GetBreachedTasks(ctx context.Context, now time.Time) ([]model.Task, error) {
+	return db.find(ctx, bson.M{
+		"status":     bson.M{"$in": bson.A{model.NotStarted, model.InProgress}},
+		"stages.sla": bson.M{"$gt": 0},
+	}, func(task model.Task) bool {
+		return task.Breached(now)
+	})
+}
+
+// mongoFilter returns MongoDB filter selecting tasks which match the query filters.
+func (q Query) mongoFilter() bson.M {
+	filter := bson.M{}
+	if q.Initiator != "" {
+		filter["initiator"] = q.Initiator
+	}
+	if len(q.Statuses) > 0 {
+		filter["status"] = bson.M{"$in": q.Statuses}
+	}
+	if q.Coordinator != "" {
+		filter["coordinators"] = q.Coordinator
+	}
+	if r := mongoRange(q.CreatedFrom, q.CreatedTo); r != nil {
+		filter["created_at"] = r
+	}
+	if r := mongoRange(q.UpdatedFrom, q.UpdatedTo); r != nil {
+		filter["updated_at"] = r
+	}
+	if q.Name != "" {
+		filter["name"] = primitive.Regex{Pattern: regexp.QuoteMeta(q.Name), Options: "i"}
+	}
+
+	return filter
 }
 
-func (db *DB) GetTaskById(taskId string) (model.Task, error) {
+// mongoAfter returns MongoDB filter selecting tasks listed after the cursor.
+func (q Query) mongoAfter(after *cursor) bson.M {
+	op := "$gt"
+	if q.Desc {
+		op = "$lt"
+	}
+
+	field := string(q.sortField())
+	// Cursor is validated when decoded.
+	value, _ := q.cursorValue(after.Value)
+	id, _ := primitive.ObjectIDFromHex(after.ID)
+
+	return bson.M{"$or": bson.A{
+		bson.M{field: bson.M{op: value}},
+		bson.M{field: value, "_id": bson.M{op: id}},
+	}}
+}
+
+func mongoRange(from, to time.Time) bson.M {
+	r := bson.M{}
+	if !from.IsZero() {
+		r["$gte"] = from
+	}
+	if !to.IsZero() {
+		r["$lt"] = to
+	}
+	if len(r) == 0 {
+		return nil
+	}
+
+	return r
+}
+
+func (db *DB) GetTaskById(ctx context.Context, taskId string) (model.Task, error) {
 	id, err := primitive.ObjectIDFromHex(taskId)
 	if err != nil {
 		return model.Task{}, err
 	}
 
 	var task model.Task
-	res := db.Db.FindOne(db.Ctx, bson.M{"_id": id})
-	if err = res.Decode(&task); err != nil {
+	res := db.Db.FindOne(ctx, bson.M{"_id": id})
+	if err = res.Decode(&task); errors.Is(err, mongo.ErrNoDocuments) {
+		return model.Task{}, ErrNotFound
+	} else if err != nil {
+		return model.Task{}, err
+	}
+	if err = open(db.Envelope, &task); err != nil {
 		return model.Task{}, err
 	}
 
 	return task, nil
 }
 
-func (db *DB) DeleteTask(taskId string) error {
+func (db *DB) DeleteTask(ctx context.Context, taskId string) error {
 	id, err := primitive.ObjectIDFromHex(taskId)
 	if err != nil {
 		return err
 	}
 
-	// TODO: check if nothing was deleted
-	_, err = db.Db.DeleteOne(db.Ctx, bson.M{"_id": id})
+	res, err := db.Db.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// UpdateTask stores task if it has not been modified since task was read. History is append-only:
+// only events recorded since the task was read are added to it.
+// On success task.Version is incremented, otherwise ErrConflict is returned.
+func (db *DB) UpdateTask(ctx context.Context, task *model.Task) error {
+	filter := versionFilter(*task)
+
+	sealed, err := seal(db.Envelope, *task)
+	if err != nil {
+		return err
+	}
+	sealed.UpdatedAt = now()
+
+	raw, err := bson.Marshal(sealed)
+	if err != nil {
+		return err
+	}
+	var fields bson.M
+	if err = bson.Unmarshal(raw, &fields); err != nil {
+		return err
+	}
+	delete(fields, "_id")
+	delete(fields, "version")
+	delete(fields, "history")
+
+	update := bson.M{
+		"$set": fields,
+		"$inc": bson.M{"version": 1},
+	}
+	if len(sealed.Recorded()) > 0 {
+		update["$push"] = bson.M{"history": bson.M{"$each": sealed.Recorded()}}
+	}
+
+	res, err := db.Db.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
 	}
+	if res.MatchedCount == 0 {
+		return ErrConflict
+	}
+
+	task.Version++
+	task.UpdatedAt = sealed.UpdatedAt
+	task.DataKey = sealed.DataKey
+	task.Saved()
 
 	return nil
 }
 
-func (db *DB) UpdateTask(task *model.Task) error {
-	filter := bson.M{"_id": task.ID}
-	update := bson.D{
-		{"$set",
-			bson.D{{"status", task.Status}, {"next", task.Next}},
-		},
+// versionFilter matches the stored copy of task if it still has the version task was read with.
+func versionFilter(task model.Task) bson.M {
+	if task.Version == 0 {
+		// Tasks stored before versioning was introduced have no version field.
+		return bson.M{"_id": task.ID, "$or": bson.A{
+			bson.M{"version": 0},
+			bson.M{"version": bson.M{"$exists": false}},
+		}}
 	}
 
-	var updatedTask model.Task
-	err := db.Db.FindOneAndUpdate(db.Ctx, filter, update).Decode(&updatedTask)
+	return bson.M{"_id": task.ID, "version": task.Version}
+}
+
+func (db *DB) AddDelegation(ctx context.Context, delegation model.Delegation) (model.Delegation, error) {
+	if delegation.ID.IsZero() {
+		delegation.ID = primitive.NewObjectID()
+	}
+	delegation.CreatedAt = now()
+
+	if _, err := delegations(db.Db).InsertOne(ctx, delegation); err != nil {
+		return model.Delegation{}, err
+	}
+
+	return delegation, nil
+}
+
+func (db *DB) GetDelegations(ctx context.Context, email string) ([]model.Delegation, error) {
+	cursor, err := delegations(db.Db).Find(ctx,
+		bson.M{"$or": bson.A{bson.M{"delegator": email}, bson.M{"delegate": email}}},
+		options.Find().SetSort(bson.D{{Key: "start", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var found []model.Delegation
+	if err = cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+func (db *DB) DeleteDelegation(ctx context.Context, delegationId, delegator string) error {
+	id, err := primitive.ObjectIDFromHex(delegationId)
+	if err != nil {
+		return err
+	}
+
+	res, err := delegations(db.Db).DeleteOne(ctx, bson.M{"_id": id, "delegator": delegator})
 	if err != nil {
 		return err
 	}
+	if res.DeletedCount == 0 {
+		return ErrDelegationNotFound
+	}
 
 	return nil
 }
+
+// delegations returns the delegation collection of the database holding tasks.
+func delegations(tasks *mongo.Collection) *mongo.Collection {
+	return tasks.Database().Collection(delegationCollection)
+}
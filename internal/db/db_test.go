@@ -0,0 +1,38 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/richard-on/task-service/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestVersionFilter(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	tests := []struct {
+		name    string
+		version int64
+		want    bson.M
+	}{
+		{
+			name:    "stored before versioning",
+			version: 0,
+			want: bson.M{"_id": id, "$or": bson.A{
+				bson.M{"version": 0},
+				bson.M{"version": bson.M{"$exists": false}},
+			}},
+		},
+		{name: "first update", version: 1, want: bson.M{"_id": id, "version": int64(1)}},
+		{name: "later update", version: 7, want: bson.M{"_id": id, "version": int64(7)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := versionFilter(model.Task{ID: id, Version: tt.version}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("versionFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
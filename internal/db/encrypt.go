@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"errors"
+	encrypt "github.com/richard-on/task-service/internal/encrypt"
+	"github.com/richard-on/task-service/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Encrypter is implemented by stores which support encryption at rest of existing tasks.
+type Encrypter interface {
+	// EncryptExisting encrypts sensitive fields of tasks stored before encryption at rest was enabled.
+	// It returns the number of encrypted tasks.
+	EncryptExisting(ctx context.Context) (int, error)
+	// RewrapExisting re-wraps data keys of tasks wrapped with a key other than the primary one,
+	// so that the key can be retired. It returns the number of re-wrapped tasks.
+	RewrapExisting(ctx context.Context) (int, error)
+}
+
+// seal returns a copy of task with sensitive fields encrypted if envelope is set.
+func seal(envelope *encrypt.Envelope, task model.Task) (model.Task, error) {
+	if envelope == nil {
+		return task, nil
+	}
+	// Fields are encrypted in place, so they must not be shared with the caller.
+	task = task.Detached()
+
+	dataKey, err := envelope.Seal(task.DataKey, task.SensitiveFields()...)
+	if err != nil {
+		return model.Task{}, err
+	}
+	task.DataKey = dataKey
+
+	return task, nil
+}
+
+// open decrypts sensitive fields of task read from the database. Tasks stored
+// without encryption are returned as is, so enabling encryption needs no downtime.
+func open(envelope *encrypt.Envelope, task *model.Task) error {
+	if task.DataKey == "" {
+		return nil
+	}
+	if envelope == nil {
+		return ErrNoEnvelope
+	}
+
+	return envelope.Open(task.DataKey, task.SensitiveFields()...)
+}
+
+// storedKey is the data key of a stored task, empty if the task is not encrypted.
+type storedKey struct {
+	ID      string `bson:"_id"`
+	DataKey string `bson:"data_key"`
+}
+
+// sealedStore is implemented by stores which support Encrypter, so that the loops
+// re-sealing existing tasks are shared by every backend.
+type sealedStore interface {
+	// storedKeys returns the data key of every stored task without decrypting it.
+	storedKeys(ctx context.Context) ([]storedKey, error)
+	GetTaskById(ctx context.Context, taskId string) (model.Task, error)
+	UpdateTask(ctx context.Context, task *model.Task) error
+}
+
+// encryptExisting implements Encrypter.EncryptExisting for store.
+func encryptExisting(ctx context.Context, store sealedStore, envelope *encrypt.Envelope) (int, error) {
+	return reseal(ctx, store, envelope, func(dataKey string) bool {
+		return dataKey == ""
+	})
+}
+
+// rewrapExisting implements Encrypter.RewrapExisting for store.
+func rewrapExisting(ctx context.Context, store sealedStore, envelope *encrypt.Envelope) (int, error) {
+	return reseal(ctx, store, envelope, func(dataKey string) bool {
+		return dataKey != "" && envelope.Stale(dataKey)
+	})
+}
+
+// reseal updates every task whose data key matches, which seals it with the primary key.
+// Tasks deleted or updated concurrently are skipped, since that update seals them as well.
+func reseal(ctx context.Context, store sealedStore, envelope *encrypt.Envelope, match func(dataKey string) bool) (int, error) {
+	if envelope == nil {
+		return 0, ErrNoEnvelope
+	}
+
+	keys, err := store.storedKeys(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for _, key := range keys {
+		if !match(key.DataKey) {
+			continue
+		}
+
+		task, err := store.GetTaskById(ctx, key.ID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		} else if err != nil {
+			return n, err
+		}
+
+		err = store.UpdateTask(ctx, &task)
+		if errors.Is(err, ErrConflict) {
+			continue
+		} else if err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+func (db *DB) EncryptExisting(ctx context.Context) (int, error) {
+	return encryptExisting(ctx, db, db.Envelope)
+}
+
+func (db *DB) RewrapExisting(ctx context.Context) (int, error) {
+	return rewrapExisting(ctx, db, db.Envelope)
+}
+
+func (db *DB) storedKeys(ctx context.Context) ([]storedKey, error) {
+	cursor, err := db.Db.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"data_key": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []storedKey
+	if err = cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
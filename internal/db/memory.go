@@ -0,0 +1,277 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/richard-on/task-service/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var _ Store = (*Memory)(nil)
+
+// Memory is a Store which keeps tasks and delegations in memory. It is meant for tests and local development.
+type Memory struct {
+	mu          sync.RWMutex
+	tasks       map[primitive.ObjectID]model.Task
+	delegations map[primitive.ObjectID]model.Delegation
+}
+
+// NewMemory creates an empty Memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		tasks:       make(map[primitive.ObjectID]model.Task),
+		delegations: make(map[primitive.ObjectID]model.Delegation),
+	}
+}
+
+func (m *Memory) AddTask(ctx context.Context, task model.Task) (model.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return model.Task{}, err
+	}
+
+	created(&task)
+	stored, err := clone(task)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored.ID.IsZero() {
+		stored.ID = primitive.NewObjectID()
+	}
+	m.tasks[stored.ID] = stored
+
+	return clone(stored)
+}
+
+func (m *Memory) ListTasks(ctx context.Context, query Query) (Page, error) {
+	tasks, err := m.filter(ctx, query.match)
+	if err != nil {
+		return Page{}, err
+	}
+
+	return query.pageOf(tasks)
+}
+
+func (m *Memory) SearchTasks(ctx context.Context, email, text string, limit int) ([]SearchResult, error) {
+	tasks, err := m.filter(ctx, func(task model.Task) bool { return task.Participant(email) })
+	if err != nil {
+		return nil, err
+	}
+
+	return rank(tasks, SearchTerms(text), limit), nil
+}
+
+func (m *Memory) GetAwaitingTasks(ctx context.Context, email string) ([]model.Task, error) {
+	return m.filter(ctx, func(task model.Task) bool { return task.CanAct(email) })
+}
+
+func (m *Memory) GetDecidedTasks(ctx context.Context, email string) ([]model.Task, error) {
+	return m.filter(ctx, func(task model.Task) bool { return task.Decided(email) })
+}
+
+// filter returns copies of stored tasks for which match returns true.
+func (m *Memory) filter(ctx context.Context, match func(task model.Task) bool) ([]model.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var tasks []model.Task
+	for _, t := range m.tasks {
+		if !match(t) {
+			continue
+		}
+
+		task, err := clone(t)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, nil
+}
+
+func (m *Memory) GetOverdueTasks(ctx context.Context, now time.Time) ([]model.Task, error) {
+	return m.filter(ctx, func(task model.Task) bool { return task.Overdue(now) })
+}
+
+func (m *Memory) GetBreachedTasks(ctx context.Context, now time.Time) ([]model.Task, error) {
+	return m.filter(ctx, func(task model.Task) bool { return task.Breached(now) })
+}
+
+func (m *Memory) GetTaskById(ctx context.Context, taskId string) (model.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return model.Task{}, err
+	}
+
+	id, err := primitive.ObjectIDFromHex(taskId)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	task, ok := m.tasks[id]
+	if !ok {
+		return model.Task{}, ErrNotFound
+	}
+
+	return clone(task)
+}
+
+func (m *Memory) UpdateTask(ctx context.Context, task *model.Task) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, ok := m.tasks[task.ID]
+	if !ok || current.Version != task.Version {
+		return ErrConflict
+	}
+
+	stored, err := clone(*task)
+	if err != nil {
+		return err
+	}
+	// Recorded events may hold strings of the request buffer, which is reused once the request is done.
+	recorded, err := cloneEvents(task.Recorded())
+	if err != nil {
+		return err
+	}
+	stored.History = append(append([]model.Event(nil), current.History...), recorded...)
+	stored.Version++
+	stored.UpdatedAt = now()
+
+	m.tasks[task.ID] = stored
+	task.Version++
+	task.UpdatedAt = stored.UpdatedAt
+	task.Saved()
+
+	return nil
+}
+
+func (m *Memory) DeleteTask(ctx context.Context, taskId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	id, err := primitive.ObjectIDFromHex(taskId)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tasks[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.tasks, id)
+
+	return nil
+}
+
+func (m *Memory) AddDelegation(ctx context.Context, delegation model.Delegation) (model.Delegation, error) {
+	if err := ctx.Err(); err != nil {
+		return model.Delegation{}, err
+	}
+
+	if delegation.ID.IsZero() {
+		delegation.ID = primitive.NewObjectID()
+	}
+	delegation.CreatedAt = now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.delegations[delegation.ID] = delegation
+
+	return delegation, nil
+}
+
+func (m *Memory) GetDelegations(ctx context.Context, email string) ([]model.Delegation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var delegations []model.Delegation
+	for _, d := range m.delegations {
+		if d.Delegator == email || d.Delegate == email {
+			delegations = append(delegations, d)
+		}
+	}
+	sortDelegations(delegations)
+
+	return delegations, nil
+}
+
+func (m *Memory) DeleteDelegation(ctx context.Context, delegationId, delegator string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	id, err := primitive.ObjectIDFromHex(delegationId)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if d, ok := m.delegations[id]; !ok || d.Delegator != delegator {
+		return ErrDelegationNotFound
+	}
+	delete(m.delegations, id)
+
+	return nil
+}
+
+// clone returns a deep copy of task as it would be read back from MongoDB.
+func clone(task model.Task) (model.Task, error) {
+	raw, err := bson.Marshal(task)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	var copied model.Task
+	if err = bson.Unmarshal(raw, &copied); err != nil {
+		return model.Task{}, err
+	}
+
+	return copied, nil
+}
+
+// cloneEvents returns a deep copy of events.
+func cloneEvents(events []model.Event) ([]model.Event, error) {
+	type history struct {
+		Events []model.Event `bson:"events"`
+	}
+
+	raw, err := bson.Marshal(history{Events: events})
+	if err != nil {
+		return nil, err
+	}
+
+	var copied history
+	if err = bson.Unmarshal(raw, &copied); err != nil {
+		return nil, err
+	}
+
+	return copied.Events, nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/richard-on/task-service/internal/model"
+)
+
+func TestMemoryUpdateTask(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+
+	task := model.Task{Name: "Contract", Status: model.NotStarted}
+	if err := task.Record(model.Event{Action: model.ActionCreated}); err != nil {
+		t.Fatal(err)
+	}
+	task, err := store.AddTask(ctx, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := store.GetTaskById(ctx, task.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := store.GetTaskById(ctx, task.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first.Name = "Lease"
+	if err = first.Record(model.Event{Action: model.ActionApproved}); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.UpdateTask(ctx, &first); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.UpdateTask(ctx, &second); err != ErrConflict {
+		t.Fatalf("update of a stale copy: err = %v, want %v", err, ErrConflict)
+	}
+
+	stored, err := store.GetTaskById(ctx, task.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "Lease" || len(stored.History) != 2 || stored.Version != first.Version {
+		t.Errorf("stored %+v, want the first update", stored)
+	}
+	if broken, err := stored.VerifyHistory(); err != nil || broken != -1 {
+		t.Errorf("VerifyHistory() = %v, %v, want an intact chain", broken, err)
+	}
+
+	if err = store.DeleteTask(ctx, task.ID.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.GetTaskById(ctx, task.ID.Hex()); err != ErrNotFound {
+		t.Errorf("get of a deleted task: err = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,330 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// ErrUnknownVersion is returned when migrating to a schema version which does not exist.
+var ErrUnknownVersion = errors.New("unknown schema version")
+
+var _ Migrator = (*DB)(nil)
+var _ Migrator = (*Postgres)(nil)
+
+// Migrator is implemented by stores which have a versioned schema.
+// Version 0 is the empty schema, before any migration was applied.
+type Migrator interface {
+	// Migrate applies all migrations which have not been applied yet.
+	Migrate(ctx context.Context) error
+	// MigrateTo applies or reverts migrations until the schema is at version.
+	MigrateTo(ctx context.Context, version int) error
+	// SchemaVersion returns the version of the latest applied migration.
+	SchemaVersion(ctx context.Context) (int, error)
+	// LatestVersion returns the version of the latest known migration.
+	LatestVersion() int
+}
+
+// schemaCollection records migrations applied to the task collection. It lives in the same database.
+const schemaCollection = "schema_migrations"
+
+// mongoMigration changes the schema of the task collection. Both directions must be safe to run
+// more than once, since several instances may start at the same time.
+type mongoMigration struct {
+	version     int
+	description string
+	up, down    func(ctx context.Context, tasks *mongo.Collection) error
+}
+
+// mongoMigrations must be sorted by version, which starts at 1 and has no gaps.
+var mongoMigrations = []mongoMigration{
+	{
+		version:     1,
+		description: "create indexes for initiator, coordinator and status lookups",
+		up: createIndexes(
+			mongo.IndexModel{
+				Keys:    bson.D{{Key: "initiator", Value: 1}},
+				Options: options.Index().SetName("initiator_1"),
+			},
+			mongo.IndexModel{
+				Keys:    bson.D{{Key: "coordinators", Value: 1}},
+				Options: options.Index().SetName("coordinators_1"),
+			},
+			mongo.IndexModel{
+				Keys:    bson.D{{Key: "status", Value: 1}, {Key: "initiator", Value: 1}},
+				Options: options.Index().SetName("status_1_initiator_1"),
+			},
+			mongo.IndexModel{
+				Keys:    bson.D{{Key: "status", Value: 1}, {Key: "coordinators", Value: 1}},
+				Options: options.Index().SetName("status_1_coordinators_1"),
+			},
+		),
+		down: dropIndexes("initiator_1", "coordinators_1", "status_1_initiator_1", "status_1_coordinators_1"),
+	},
+	{
+		version:     2,
+		description: "create index for lookups of tasks decided by a coordinator",
+		up: createIndexes(mongo.IndexModel{
+			Keys:    bson.D{{Key: "stages.decisions.coordinator", Value: 1}},
+			Options: options.Index().SetName("stages.decisions.coordinator_1"),
+		}),
+		down: dropIndexes("stages.decisions.coordinator_1"),
+	},
+	{
+		version:     3,
+		description: "backfill task timestamps and statuses, create indexes for sorted listing",
+		up: func(ctx context.Context, tasks *mongo.Collection) error {
+			// Tasks stored before timestamps were introduced get the creation time encoded in their ObjectID.
+			_, err := tasks.UpdateMany(ctx, bson.M{"created_at": bson.M{"$exists": false}}, mongo.Pipeline{
+				{{Key: "$set", Value: bson.M{
+					"created_at": bson.M{"$toDate": "$_id"},
+					"updated_at": bson.M{"$toDate": "$_id"},
+				}}},
+			})
+			if err != nil {
+				return err
+			}
+
+			// Numeric statuses can be read, but can't be filtered or sorted along with string ones.
+			// The mapping is copied here, so that the migration does not change along with the model.
+			for legacy, status := range map[int]string{1: "not_started", 2: "in_progress", 3: "approved", 4: "declined"} {
+				_, err = tasks.UpdateMany(ctx, bson.M{"status": legacy}, bson.M{"$set": bson.M{"status": status}})
+				if err != nil {
+					return err
+				}
+			}
+
+			return createIndexes(
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "initiator", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
+					Options: options.Index().SetName("initiator_1_created_at_1__id_1"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "initiator", Value: 1}, {Key: "updated_at", Value: 1}, {Key: "_id", Value: 1}},
+					Options: options.Index().SetName("initiator_1_updated_at_1__id_1"),
+				},
+			)(ctx, tasks)
+		},
+		// Backfilled values are valid for the previous schema as well.
+		down: dropIndexes("initiator_1_created_at_1__id_1", "initiator_1_updated_at_1__id_1"),
+	},
+	{
+		version:     4,
+		description: "create text index for task search",
+		up: createIndexes(mongo.IndexModel{
+			Keys: bson.D{{Key: "name", Value: "text"}, {Key: "description", Value: "text"}},
+			Options: options.Index().
+				SetName("name_text_description_text").
+				SetWeights(bson.M{"name": nameWeight, "description": 1}).
+				SetDefaultLanguage("none"),
+		}),
+		down: dropIndexes("name_text_description_text"),
+	},
+	{
+		version:     5,
+		description: "create index for lookups of overdue tasks",
+		up: createIndexes(mongo.IndexModel{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "due_at", Value: 1}},
+			Options: options.Index().SetName("status_1_due_at_1"),
+		}),
+		down: dropIndexes("status_1_due_at_1"),
+	},
+	{
+		version:     6,
+		description: "create indexes for delegation lookups and decisions made by delegates",
+		up: func(ctx context.Context, tasks *mongo.Collection) error {
+			err := createIndexes(mongo.IndexModel{
+				Keys:    bson.D{{Key: "stages.decisions.decided_by", Value: 1}},
+				Options: options.Index().SetName("stages.decisions.decided_by_1"),
+			})(ctx, tasks)
+			if err != nil {
+				return err
+			}
+
+			return createIndexes(
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "delegator", Value: 1}, {Key: "start", Value: 1}},
+					Options: options.Index().SetName("delegator_1_start_1"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "delegate", Value: 1}, {Key: "start", Value: 1}},
+					Options: options.Index().SetName("delegate_1_start_1"),
+				},
+			)(ctx, delegations(tasks))
+		},
+		down: func(ctx context.Context, tasks *mongo.Collection) error {
+			if err := dropIndexes("stages.decisions.decided_by_1")(ctx, tasks); err != nil {
+				return err
+			}
+
+			return dropIndexes("delegator_1_start_1", "delegate_1_start_1")(ctx, delegations(tasks))
+		},
+	},
+	{
+		version:     7,
+		description: "create indexes for lookups of decisions on previous revisions",
+		up: createIndexes(
+			mongo.IndexModel{
+				Keys:    bson.D{{Key: "revisions.stages.decisions.coordinator", Value: 1}},
+				Options: options.Index().SetName("revisions.stages.decisions.coordinator_1"),
+			},
+			mongo.IndexModel{
+				Keys:    bson.D{{Key: "revisions.stages.decisions.decided_by", Value: 1}},
+				Options: options.Index().SetName("revisions.stages.decisions.decided_by_1"),
+			},
+		),
+		down: dropIndexes("revisions.stages.decisions.coordinator_1", "revisions.stages.decisions.decided_by_1"),
+	},
+	{
+		version:     8,
+		description: "convert tasks stored before approval stages into single stage tasks",
+		up: func(ctx context.Context, tasks *mongo.Collection) error {
+			// Tasks stored before approval modes have no decisions. Their coordinators before next
+			// have approved and the one at next has made the final decision, if the task is finished.
+			approvals := bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$status", "approved"}},
+				bson.M{"$add": bson.A{"$next", 1}},
+				"$next",
+			}}
+			legacyDecisions := bson.M{"$concatArrays": bson.A{
+				bson.M{"$map": bson.M{
+					"input": bson.M{"$range": bson.A{0, bson.M{"$min": bson.A{approvals, bson.M{"$size": "$coordinators"}}}}},
+					"in":    bson.M{"coordinator": bson.M{"$arrayElemAt": bson.A{"$coordinators", "$$this"}}, "approved": true},
+				}},
+				bson.M{"$cond": bson.A{
+					bson.M{"$eq": bson.A{"$status", "declined"}},
+					bson.A{bson.M{"coordinator": bson.M{"$arrayElemAt": bson.A{"$coordinators", "$next"}}, "approved": false}},
+					bson.A{},
+				}},
+			}}
+
+			// Approval modes kept their mode, quorum and decisions on the task itself.
+			_, err := tasks.UpdateMany(ctx, bson.M{"stages": bson.M{"$exists": false}}, mongo.Pipeline{
+				{{Key: "$set", Value: bson.M{
+					"coordinators": bson.M{"$ifNull": bson.A{"$coordinators", bson.A{}}},
+					"next":         bson.M{"$ifNull": bson.A{"$next", 0}},
+				}}},
+				{{Key: "$set", Value: bson.M{
+					"stages": bson.A{bson.M{
+						"coordinators": "$coordinators",
+						"mode":         bson.M{"$ifNull": bson.A{"$mode", "sequential"}},
+						"quorum":       bson.M{"$ifNull": bson.A{"$quorum", 0}},
+						"decisions":    bson.M{"$ifNull": bson.A{"$decisions", legacyDecisions}},
+						"next":         "$next",
+					}},
+					"stage": 0,
+				}}},
+				{{Key: "$unset", Value: bson.A{"mode", "quorum", "decisions", "next"}}},
+			})
+
+			return err
+		},
+		// Converted tasks are valid for the previous schema as well.
+		down: func(ctx context.Context, tasks *mongo.Collection) error {
+			return nil
+		},
+	},
+}
+
+func (db *DB) Migrate(ctx context.Context) error {
+	return db.MigrateTo(ctx, db.LatestVersion())
+}
+
+func (db *DB) LatestVersion() int {
+	return len(mongoMigrations)
+}
+
+func (db *DB) SchemaVersion(ctx context.Context) (int, error) {
+	var applied struct {
+		Version int `bson:"_id"`
+	}
+
+	err := db.Db.Database().Collection(schemaCollection).FindOne(ctx, bson.M{},
+		options.FindOne().SetSort(bson.M{"_id": -1})).Decode(&applied)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return applied.Version, nil
+}
+
+func (db *DB) MigrateTo(ctx context.Context, version int) error {
+	if version < 0 || version > db.LatestVersion() {
+		return fmt.Errorf("%w: %v", ErrUnknownVersion, version)
+	}
+
+	current, err := db.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	versions := db.Db.Database().Collection(schemaCollection)
+
+	for _, m := range mongoMigrations {
+		if m.version <= current || m.version > version {
+			continue
+		}
+
+		if err = m.up(ctx, db.Db); err != nil {
+			return fmt.Errorf("migration %v: %w", m.version, err)
+		}
+
+		_, err = versions.InsertOne(ctx, bson.M{
+			"_id":         m.version,
+			"description": m.description,
+			"applied_at":  time.Now().UTC(),
+		})
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("migration %v: %w", m.version, err)
+		}
+		db.Log.Infof("applied migration %v: %v", m.version, m.description)
+	}
+
+	for i := len(mongoMigrations) - 1; i >= 0; i-- {
+		m := mongoMigrations[i]
+		if m.version > current || m.version <= version {
+			continue
+		}
+
+		if err = m.down(ctx, db.Db); err != nil {
+			return fmt.Errorf("migration %v: %w", m.version, err)
+		}
+
+		if _, err = versions.DeleteOne(ctx, bson.M{"_id": m.version}); err != nil {
+			return fmt.Errorf("migration %v: %w", m.version, err)
+		}
+		db.Log.Infof("reverted migration %v: %v", m.version, m.description)
+	}
+
+	return nil
+}
+
+func createIndexes(indexes ...mongo.IndexModel) func(ctx context.Context, tasks *mongo.Collection) error {
+	return func(ctx context.Context, tasks *mongo.Collection) error {
+		_, err := tasks.Indexes().CreateMany(ctx, indexes)
+		return err
+	}
+}
+
+func dropIndexes(names ...string) func(ctx context.Context, tasks *mongo.Collection) error {
+	return func(ctx context.Context, tasks *mongo.Collection) error {
+		for _, name := range names {
+			_, err := tasks.Indexes().DropOne(ctx, name)
+
+			// Index which does not exist has already been dropped.
+			var cmdErr mongo.CommandError
+			if errors.As(err, &cmdErr) && cmdErr.Name == "IndexNotFound" {
+				continue
+			} else if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
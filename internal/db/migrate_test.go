@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSQLMigrationList(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		versions []int
+		wantErr  bool
+	}{
+		{
+			name: "sorted by version",
+			files: fstest.MapFS{
+				"m/0010_later.up.sql":   {Data: []byte("up 10")},
+				"m/0010_later.down.sql": {Data: []byte("down 10")},
+				"m/0002_next.up.sql":    {Data: []byte("up 2")},
+				"m/0002_next.down.sql":  {Data: []byte("down 2")},
+				"m/0001_init.up.sql":    {Data: []byte("up 1")},
+				"m/0001_init.down.sql":  {Data: []byte("down 1")},
+			},
+			versions: []int{1, 2, 10},
+		},
+		{name: "empty", files: fstest.MapFS{"m": {Mode: fs.ModeDir}}, versions: []int{}},
+		{
+			name:    "missing down script",
+			files:   fstest.MapFS{"m/0001_init.up.sql": {Data: []byte("up 1")}},
+			wantErr: true,
+		},
+		{
+			name:    "missing up script",
+			files:   fstest.MapFS{"m/0001_init.down.sql": {Data: []byte("down 1")}},
+			wantErr: true,
+		},
+		{
+			name:    "no version",
+			files:   fstest.MapFS{"m/init.up.sql": {Data: []byte("up")}, "m/init.down.sql": {Data: []byte("down")}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := sqlMigrationList(tt.files, "m")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("sqlMigrationList() = %+v, want an error", migrations)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(migrations) != len(tt.versions) {
+				t.Fatalf("sqlMigrationList() returned %v migrations, want %v", len(migrations), len(tt.versions))
+			}
+			for i, m := range migrations {
+				if m.version != tt.versions[i] || m.up == "" || m.down == "" {
+					t.Errorf("migration %v = %+v, want version %v with both scripts", i, m, tt.versions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := postgresMigrationList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("PostgreSQL migration %v has version %v, want %v", m.name, m.version, i+1)
+		}
+	}
+	if got := (&Postgres{}).LatestVersion(); got != len(migrations) {
+		t.Errorf("PostgreSQL LatestVersion() = %v, want %v", got, len(migrations))
+	}
+
+	for i, m := range mongoMigrations {
+		if m.version != i+1 || m.up == nil || m.down == nil {
+			t.Errorf("MongoDB migration %v has version %v, want %v with both directions", m.description, m.version, i+1)
+		}
+	}
+}
+
+func TestMigrateToUnknownVersion(t *testing.T) {
+	for name, migrator := range map[string]Migrator{"mongodb": &DB{}, "postgres": &Postgres{}} {
+		for _, version := range []int{-1, migrator.LatestVersion() + 1} {
+			if err := migrator.MigrateTo(context.Background(), version); !errors.Is(err, ErrUnknownVersion) {
+				t.Errorf("%v: MigrateTo(%v) err = %v, want %v", name, version, err, ErrUnknownVersion)
+			}
+		}
+	}
+}
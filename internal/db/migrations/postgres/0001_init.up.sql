@@ -0,0 +1,46 @@
+-- Tasks keep searchable columns alongside the full document, which holds
+-- everything else, such as stage configuration.
+CREATE TABLE tasks (
+    id          CHAR(24) PRIMARY KEY,
+    name        TEXT     NOT NULL,
+    description TEXT     NOT NULL,
+    initiator   TEXT     NOT NULL,
+    status      TEXT     NOT NULL,
+    stage       INTEGER  NOT NULL,
+    version     BIGINT   NOT NULL,
+    data_key    TEXT     NOT NULL DEFAULT '',
+    document    JSONB    NOT NULL
+);
+
+CREATE INDEX tasks_initiator_idx ON tasks (initiator);
+
+CREATE TABLE task_coordinators (
+    task_id  CHAR(24) NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    stage    INTEGER  NOT NULL,
+    position INTEGER  NOT NULL,
+    email    TEXT     NOT NULL,
+    PRIMARY KEY (task_id, stage, position)
+);
+
+CREATE INDEX task_coordinators_email_idx ON task_coordinators (email);
+
+-- A coordinator decides at most once per stage and decisions are never changed.
+CREATE TABLE task_decisions (
+    task_id      CHAR(24)    NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    stage        INTEGER     NOT NULL,
+    coordinator  TEXT        NOT NULL,
+    approved     BOOLEAN     NOT NULL,
+    decided_at   TIMESTAMPTZ NOT NULL,
+    content_hash TEXT        NOT NULL DEFAULT '',
+    signature    TEXT        NOT NULL DEFAULT '',
+    PRIMARY KEY (task_id, stage, coordinator)
+);
+
+-- Audit trail is append-only, events are stored in the form they were hashed in.
+CREATE TABLE task_events (
+    task_id CHAR(24) NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    seq     INTEGER  NOT NULL,
+    hash    TEXT     NOT NULL,
+    event   JSONB    NOT NULL,
+    PRIMARY KEY (task_id, seq)
+);
@@ -0,0 +1 @@
+DROP INDEX tasks_status_initiator_idx;
@@ -0,0 +1,2 @@
+-- Lists of tasks are usually filtered by status along with the initiator.
+CREATE INDEX tasks_status_initiator_idx ON tasks (status, initiator);
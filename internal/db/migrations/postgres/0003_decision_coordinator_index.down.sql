@@ -0,0 +1 @@
+DROP INDEX task_decisions_coordinator_idx;
@@ -0,0 +1,2 @@
+-- Coordinators look up tasks they have decided on.
+CREATE INDEX task_decisions_coordinator_idx ON task_decisions (coordinator);
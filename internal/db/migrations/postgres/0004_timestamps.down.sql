@@ -0,0 +1,6 @@
+DROP INDEX tasks_initiator_updated_idx;
+DROP INDEX tasks_initiator_created_idx;
+
+ALTER TABLE tasks
+    DROP COLUMN updated_at,
+    DROP COLUMN created_at;
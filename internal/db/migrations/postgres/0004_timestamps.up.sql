@@ -0,0 +1,15 @@
+ALTER TABLE tasks
+    ADD COLUMN created_at TIMESTAMPTZ,
+    ADD COLUMN updated_at TIMESTAMPTZ;
+
+-- Tasks stored before timestamps were introduced get the creation time encoded in their ObjectID.
+UPDATE tasks SET
+    created_at = to_timestamp(('x' || lpad(substr(id, 1, 8), 16, '0'))::bit(64)::bigint),
+    updated_at = to_timestamp(('x' || lpad(substr(id, 1, 8), 16, '0'))::bit(64)::bigint);
+
+ALTER TABLE tasks
+    ALTER COLUMN created_at SET NOT NULL,
+    ALTER COLUMN updated_at SET NOT NULL;
+
+CREATE INDEX tasks_initiator_created_idx ON tasks (initiator, created_at, id);
+CREATE INDEX tasks_initiator_updated_idx ON tasks (initiator, updated_at, id);
@@ -0,0 +1,4 @@
+ALTER TABLE tasks ADD COLUMN due_at TIMESTAMPTZ;
+
+-- The expiry worker only looks for tasks which still await decisions.
+CREATE INDEX tasks_due_at_idx ON tasks (due_at) WHERE status IN ('not_started', 'in_progress');
@@ -0,0 +1,5 @@
+DROP INDEX task_decisions_decided_by_idx;
+
+ALTER TABLE task_decisions DROP COLUMN decided_by;
+
+DROP TABLE delegations;
@@ -0,0 +1,16 @@
+-- A delegate decides on behalf of the coordinator while the delegation is in effect.
+CREATE TABLE delegations (
+    id         CHAR(24)    PRIMARY KEY,
+    delegator  TEXT        NOT NULL,
+    delegate   TEXT        NOT NULL,
+    starts_at  TIMESTAMPTZ NOT NULL,
+    ends_at    TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX delegations_delegator_idx ON delegations (delegator, starts_at);
+CREATE INDEX delegations_delegate_idx ON delegations (delegate, starts_at);
+
+ALTER TABLE task_decisions ADD COLUMN decided_by TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX task_decisions_decided_by_idx ON task_decisions (decided_by) WHERE decided_by <> '';
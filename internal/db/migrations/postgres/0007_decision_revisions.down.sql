@@ -0,0 +1,7 @@
+-- Only decisions on the first revision fit the previous key.
+DELETE FROM task_decisions WHERE revision > 1;
+
+ALTER TABLE task_decisions DROP CONSTRAINT task_decisions_pkey;
+ALTER TABLE task_decisions ADD PRIMARY KEY (task_id, stage, coordinator);
+
+ALTER TABLE task_decisions DROP COLUMN revision;
@@ -0,0 +1,5 @@
+-- A resubmitted task is decided on anew, so a coordinator decides at most once per stage of each revision.
+ALTER TABLE task_decisions ADD COLUMN revision INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE task_decisions DROP CONSTRAINT task_decisions_pkey;
+ALTER TABLE task_decisions ADD PRIMARY KEY (task_id, revision, stage, coordinator);
@@ -0,0 +1 @@
+ALTER TABLE task_decisions DROP COLUMN comment;
@@ -0,0 +1 @@
+ALTER TABLE task_decisions ADD COLUMN comment TEXT NOT NULL DEFAULT '';
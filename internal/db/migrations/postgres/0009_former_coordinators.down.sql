@@ -0,0 +1 @@
+DELETE FROM task_coordinators WHERE stage = -1;
@@ -0,0 +1,6 @@
+-- Coordinators who are no longer in any stage, because they were substituted or belong to
+-- previous revisions, are kept with stage -1 at their position in the task coordinators.
+INSERT INTO task_coordinators (task_id, stage, position, email)
+SELECT t.id, -1, c.ord - 1, c.email
+FROM tasks t, jsonb_array_elements_text(t.document -> 'coordinators') WITH ORDINALITY AS c (email, ord)
+WHERE NOT EXISTS (SELECT 1 FROM task_coordinators tc WHERE tc.task_id = t.id AND tc.email = c.email);
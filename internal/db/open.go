@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/richard-on/task-service/config"
+	encrypt "github.com/richard-on/task-service/internal/encrypt"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	BackendMongo    = "mongo"
+	BackendPostgres = "postgres"
+	BackendBolt     = "bolt"
+	BackendMemory   = "memory"
+)
+
+// ErrPrefork is returned when an in-process backend is used with Fiber prefork,
+// as every child process would get a store of its own.
+var ErrPrefork = errors.New("in-process storage backend does not support FIBER_PREFORK")
+
+// Open connects to the storage backend selected by config.DbBackend and returns the store
+// along with a function which closes the connection. ctx is only used while connecting.
+// Migrations are not applied, stores with a schema implement Migrator. If envelope is set, sensitive fields are encrypted at rest.
+func Open(ctx context.Context, envelope *encrypt.Envelope) (Store, func() error, error) {
+	switch config.DbBackend {
+	case BackendPostgres:
+		conn, err := ConnectPostgres(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		store := NewPostgres(conn)
+		store.Envelope = envelope
+
+		return store, conn.Close, nil
+
+	case BackendBolt:
+		if config.FiberPrefork {
+			return nil, nil, ErrPrefork
+		}
+
+		store, err := OpenBolt(config.BoltPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		store.Envelope = envelope
+
+		return store, store.Close, nil
+
+	case BackendMemory:
+		if config.FiberPrefork {
+			return nil, nil, ErrPrefork
+		}
+
+		// Tasks are lost on restart, so there is nothing to encrypt at rest.
+		return NewMemory(), func() error { return nil }, nil
+
+	case BackendMongo, "":
+		client, collection, err := Connect(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		store := NewDatabase(collection)
+		store.Envelope = envelope
+
+		return store, func() error { return client.Disconnect(ctx) }, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown database backend %q", config.DbBackend)
+	}
+}
+
+// IsTimeout reports whether err means that a storage call didn't finish before its deadline.
+func IsTimeout(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// query_canceled is reported when a statement is interrupted by its context.
+		return pqErr.Code == "57014"
+	}
+
+	return errors.Is(err, context.DeadlineExceeded) || mongo.IsTimeout(err)
+}
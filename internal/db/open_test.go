@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestIsTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "deadline", err: context.DeadlineExceeded, want: true},
+		{name: "wrapped deadline", err: fmt.Errorf("find: %w", context.DeadlineExceeded), want: true},
+		{name: "query canceled", err: &pq.Error{Code: "57014"}, want: true},
+		{name: "other postgres error", err: &pq.Error{Code: "23505"}},
+		{name: "canceled", err: context.Canceled},
+		{name: "not found", err: ErrNotFound},
+		{name: "other", err: errors.New("connection refused")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTimeout(tt.err); got != tt.want {
+				t.Errorf("IsTimeout(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryContext(t *testing.T) {
+	store := NewMemory()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := store.ListTasks(ctx, Query{Initiator: "initiator"}); err != context.Canceled {
+		t.Errorf("ListTasks() err = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,792 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/richard-on/task-service/config"
+	encrypt "github.com/richard-on/task-service/internal/encrypt"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/postgres/*.sql
+var postgresMigrations embed.FS
+
+var _ Store = (*Postgres)(nil)
+var _ Encrypter = (*Postgres)(nil)
+
+// Postgres is a Store backed by PostgreSQL. Task stages and other nested data are kept
+// in a JSONB document, while coordinators, decisions and history are also stored in their own tables.
+// If Envelope is set, sensitive task fields are encrypted at rest.
+type Postgres struct {
+	Db       *sql.DB
+	Log      logger.Logger
+	Envelope *encrypt.Envelope
+}
+
+// NewPostgres creates a Postgres store using an open database handle.
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{
+		Db: db,
+		Log: logger.NewLogger(
+			config.DefaultWriter,
+			config.LogInfo.Level,
+			"task-postgres"),
+	}
+}
+
+// ConnectPostgres opens a connection pool to PostgreSQL described by config.
+func ConnectPostgres(ctx context.Context) (*sql.DB, error) {
+	db, err := sql.Open("postgres", config.PostgresConnString)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// sqlMigration is a pair of embedded SQL scripts, NNNN_name.up.sql and NNNN_name.down.sql.
+type sqlMigration struct {
+	version  int
+	name     string
+	up, down string
+}
+
+// postgresMigrationList returns embedded migrations sorted by version.
+func postgresMigrationList() ([]sqlMigration, error) {
+	return sqlMigrationList(postgresMigrations, "migrations/postgres")
+}
+
+// sqlMigrationList reads pairs of migration scripts from dir of fsys and returns them sorted by version.
+func sqlMigrationList(fsys fs.FS, dir string) ([]sqlMigration, error) {
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*sqlMigration)
+	for _, f := range files {
+		var version int
+		if _, err = fmt.Sscanf(f.Name(), "%d_", &version); err != nil {
+			return nil, fmt.Errorf("migration %v: %w", f.Name(), err)
+		}
+
+		script, err := fs.ReadFile(fsys, path.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &sqlMigration{version: version}
+			byVersion[version] = m
+		}
+
+		switch {
+		case strings.HasSuffix(f.Name(), ".up.sql"):
+			m.name = strings.TrimSuffix(f.Name(), ".up.sql")
+			m.up = string(script)
+		case strings.HasSuffix(f.Name(), ".down.sql"):
+			m.down = string(script)
+		}
+	}
+
+	migrations := make([]sqlMigration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %v: both up and down scripts are required", m.version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+
+	return migrations, nil
+}
+
+// Migrate applies embedded SQL migrations which have not been applied yet.
+func (p *Postgres) Migrate(ctx context.Context) error {
+	return p.MigrateTo(ctx, p.LatestVersion())
+}
+
+func (p *Postgres) LatestVersion() int {
+	migrations, err := postgresMigrationList()
+	if err != nil || len(migrations) == 0 {
+		return 0
+	}
+
+	return migrations[len(migrations)-1].version
+}
+
+func (p *Postgres) SchemaVersion(ctx context.Context) (int, error) {
+	if err := p.createSchemaTable(ctx); err != nil {
+		return 0, err
+	}
+
+	var version int
+	err := p.Db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+
+	return version, err
+}
+
+// MigrateTo applies or reverts migrations until the schema is at version. Each migration runs in
+// its own transaction, which also locks schema_migrations, so that concurrent runs wait for each other.
+func (p *Postgres) MigrateTo(ctx context.Context, version int) error {
+	migrations, err := postgresMigrationList()
+	if err != nil {
+		return err
+	}
+	if version < 0 || version > p.LatestVersion() {
+		return fmt.Errorf("%w: %v", ErrUnknownVersion, version)
+	}
+
+	if err = p.createSchemaTable(ctx); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version > version {
+			break
+		}
+
+		err = p.inTx(ctx, func(tx *sql.Tx) error {
+			applied, err := p.lockSchema(ctx, tx, m.version)
+			if err != nil || applied {
+				return err
+			}
+
+			if _, err = tx.ExecContext(ctx, m.up); err != nil {
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, m.version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %v: %w", m.name, err)
+		}
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.version <= version {
+			break
+		}
+
+		err = p.inTx(ctx, func(tx *sql.Tx) error {
+			applied, err := p.lockSchema(ctx, tx, m.version)
+			if err != nil || !applied {
+				return err
+			}
+
+			if _, err = tx.ExecContext(ctx, m.down); err != nil {
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %v: %w", m.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (p *Postgres) createSchemaTable(ctx context.Context) error {
+	_, err := p.Db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+
+	return err
+}
+
+// lockSchema locks schema_migrations until the end of tx and reports whether version is applied.
+func (p *Postgres) lockSchema(ctx context.Context, tx *sql.Tx, version int) (bool, error) {
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE schema_migrations IN EXCLUSIVE MODE`); err != nil {
+		return false, err
+	}
+
+	var applied bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, version).Scan(&applied)
+
+	return applied, err
+}
+
+func (p *Postgres) AddTask(ctx context.Context, task model.Task) (model.Task, error) {
+	created(&task)
+
+	sealed, err := seal(p.Envelope, task)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	document, err := taskDocument(sealed)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	err = p.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO tasks
+			(id, name, description, initiator, status, stage, version, data_key, document, created_at, updated_at, due_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+			sealed.ID.Hex(), sealed.Name, sealed.Description, sealed.Initiator, sealed.Status,
+			sealed.Stage, sealed.Version, sealed.DataKey, string(document), sealed.CreatedAt, sealed.UpdatedAt, sealed.DueAt)
+		if err != nil {
+			return err
+		}
+
+		if err = p.writeCoordinators(ctx, tx, sealed); err != nil {
+			return err
+		}
+		if err = p.writeDecisions(ctx, tx, sealed); err != nil {
+			return err
+		}
+
+		return p.appendEvents(ctx, tx, sealed.ID, 0, sealed.History)
+	})
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	task.DataKey = sealed.DataKey
+	task.Saved()
+
+	return task, nil
+}
+
+// ListTasks filters, sorts and pages tasks in PostgreSQL. Pages are selected by the sort value
+// and ID of the last task of the previous page, so they stay stable while tasks are added.
+func (p *Postgres) ListTasks(ctx context.Context, query Query) (Page, error) {
+	if err := query.validate(); err != nil {
+		return Page{}, err
+	}
+	if p.Envelope != nil && query.usesName() {
+		return Page{}, ErrEncryptedField
+	}
+
+	after, err := query.cursor()
+	if err != nil {
+		return Page{}, err
+	}
+
+	where, args := query.sqlWhere()
+
+	var total int64
+	err = p.Db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tasks WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		return Page{}, err
+	}
+
+	// Sort field is one of the known column names, so it is safe to put into the query.
+	column := string(query.sortField())
+	op, order := ">", "ASC"
+	if query.Desc {
+		op, order = "<", "DESC"
+	}
+
+	if after != nil {
+		value, _ := query.cursorValue(after.Value)
+		args = append(args, value, after.ID)
+		where += fmt.Sprintf(" AND (%v, id) %v ($%d, $%d)", column, op, len(args)-1, len(args))
+	}
+	args = append(args, query.limit()+1)
+
+	tasks, err := p.queryTasks(ctx, fmt.Sprintf(`SELECT id, data_key, version, created_at, updated_at, document
+		FROM tasks WHERE %v ORDER BY %v %v, id %v LIMIT $%d`, where, column, order, order, len(args)), args...)
+	if err != nil {
+		return Page{}, err
+	}
+
+	page, next := query.nextCursor(tasks)
+
+	return Page{Tasks: page, NextCursor: next, Total: total}, nil
+}
+
+// sqlWhere returns SQL condition selecting tasks which match the query filters along with its arguments.
+func (q Query) sqlWhere() (string, []interface{}) {
+	conditions := []string{"TRUE"}
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if q.Initiator != "" {
+		add("initiator = $%d", q.Initiator)
+	}
+	if len(q.Statuses) > 0 {
+		statuses := make([]string, len(q.Statuses))
+		for i, s := range q.Statuses {
+			statuses[i] = string(s)
+		}
+		add("status = ANY($%d)", pq.Array(statuses))
+	}
+	if q.Coordinator != "" {
+		add("id IN (SELECT task_id FROM task_coordinators WHERE email = $%d)", q.Coordinator)
+	}
+	if !q.CreatedFrom.IsZero() {
+		add("created_at >= $%d", q.CreatedFrom)
+	}
+	if !q.CreatedTo.IsZero() {
+		add("created_at < $%d", q.CreatedTo)
+	}
+	if !q.UpdatedFrom.IsZero() {
+		add("updated_at >= $%d", q.UpdatedFrom)
+	}
+	if !q.UpdatedTo.IsZero() {
+		add("updated_at < $%d", q.UpdatedTo)
+	}
+	if q.Name != "" {
+		add("strpos(lower(name), lower($%d)) > 0", q.Name)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// SearchTasks ranks tasks visible to email in the service, since names and descriptions
+// are not indexed for full-text search in PostgreSQL.
+func (p *Postgres) SearchTasks(ctx context.Context, email, text string, limit int) ([]SearchResult, error) {
+	tasks, err := p.queryTasks(ctx, `SELECT id, data_key, version, created_at, updated_at, document FROM tasks
+		WHERE initiator = $1 OR id IN (SELECT task_id FROM task_coordinators WHERE email = $1)`, email)
+	if err != nil {
+		return nil, err
+	}
+
+	return rank(tasks, SearchTerms(text), limit), nil
+}
+
+// GetAwaitingTasks looks tasks up by their coordinators. Whether the coordinator may act
+// depends on stage configuration and is checked after the tasks are read.
+func (p *Postgres) GetAwaitingTasks(ctx context.Context, email string) ([]model.Task, error) {
+	tasks, err := p.queryTasks(ctx, `SELECT id, data_key, version, created_at, updated_at, document FROM tasks
+		WHERE status IN ($2, $3) AND id IN (SELECT task_id FROM task_coordinators WHERE email = $1)
+		ORDER BY id`, email, model.NotStarted, model.InProgress)
+	if err != nil {
+		return nil, err
+	}
+
+	awaiting := tasks[:0]
+	for _, task := range tasks {
+		if task.CanAct(email) {
+			awaiting = append(awaiting, task)
+		}
+	}
+
+	return awaiting, nil
+}
+
+func (p *Postgres) GetDecidedTasks(ctx context.Context, email string) ([]model.Task, error) {
+	return p.queryTasks(ctx, `SELECT id, data_key, version, created_at, updated_at, document FROM tasks
+		WHERE id IN (SELECT task_id FROM task_decisions WHERE coordinator = $1 OR decided_by = $1) ORDER BY id`, email)
+}
+
+func (p *Postgres) GetOverdueTasks(ctx context.Context, now time.Time) ([]model.Task, error) {
+	tasks, err := p.queryTasks(ctx, `SELECT id, data_key, version, created_at, updated_at, document FROM tasks
+		WHERE due_at <= $1 AND status IN ($2, $3) ORDER BY due_at`, now, model.NotStarted, model.InProgress)
+	if err != nil {
+		return nil, err
+	}
+
+	overdue := tasks[:0]
+	for _, task := range tasks {
+		if task.Overdue(now) {
+			overdue = append(overdue, task)
+		}
+	}
+
+	return overdue, nil
+}
+
+// GetBreachedTasks selects unfinished tasks with an SLA on any stage. Whether a coordinator of the current
+// stage is past its SLA depends on the task history and is checked after the tasks are read.
+func (p *Postgres) GetBreachedTasks(ctx context.Context, now time.Time) ([]model.Task, error) {
+	tasks, err := p.queryTasks(ctx, `SELECT id, data_key, version, created_at, updated_at, document FROM tasks
+		WHERE status IN ($1, $2)
+		AND EXISTS (SELECT 1 FROM jsonb_array_elements(document->'stages') AS s WHERE s ? 'sla')`,
+		model.NotStarted, model.InProgress)
+	if err != nil {
+		return nil, err
+	}
+
+	breached := tasks[:0]
+	for _, task := range tasks {
+		if task.Breached(now) {
+			breached = append(breached, task)
+		}
+	}
+
+	return breached, nil
+}
+
+func (p *Postgres) GetTaskById(ctx context.Context, taskId string) (model.Task, error) {
+	if _, err := primitive.ObjectIDFromHex(taskId); err != nil {
+		return model.Task{}, err
+	}
+
+	tasks, err := p.queryTasks(ctx, `SELECT id, data_key, version, created_at, updated_at, document FROM tasks WHERE id = $1`, taskId)
+	if err != nil {
+		return model.Task{}, err
+	}
+	if len(tasks) == 0 {
+		return model.Task{}, ErrNotFound
+	}
+
+	return tasks[0], nil
+}
+
+// UpdateTask stores task in a single transaction, so that a decision is either stored
+// along with its history event and the new task status, or not at all.
+func (p *Postgres) UpdateTask(ctx context.Context, task *model.Task) error {
+	sealed, err := seal(p.Envelope, *task)
+	if err != nil {
+		return err
+	}
+	sealed.UpdatedAt = now()
+
+	document, err := taskDocument(sealed)
+	if err != nil {
+		return err
+	}
+
+	err = p.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `UPDATE tasks SET
+			name = $3, description = $4, initiator = $5, status = $6, stage = $7,
+			version = version + 1, data_key = $8, document = $9, updated_at = $10, due_at = $11
+			WHERE id = $1 AND version = $2`,
+			sealed.ID.Hex(), sealed.Version, sealed.Name, sealed.Description, sealed.Initiator,
+			sealed.Status, sealed.Stage, sealed.DataKey, string(document), sealed.UpdatedAt, sealed.DueAt)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrConflict
+		}
+
+		if err = p.writeCoordinators(ctx, tx, sealed); err != nil {
+			return err
+		}
+		if err = p.writeDecisions(ctx, tx, sealed); err != nil {
+			return err
+		}
+
+		recorded := sealed.Recorded()
+		return p.appendEvents(ctx, tx, sealed.ID, len(sealed.History)-len(recorded), recorded)
+	})
+	if err != nil {
+		return err
+	}
+
+	task.Version++
+	task.UpdatedAt = sealed.UpdatedAt
+	task.DataKey = sealed.DataKey
+	task.Saved()
+
+	return nil
+}
+
+func (p *Postgres) DeleteTask(ctx context.Context, taskId string) error {
+	if _, err := primitive.ObjectIDFromHex(taskId); err != nil {
+		return err
+	}
+
+	res, err := p.Db.ExecContext(ctx, `DELETE FROM tasks WHERE id = $1`, taskId)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (p *Postgres) EncryptExisting(ctx context.Context) (int, error) {
+	return encryptExisting(ctx, p, p.Envelope)
+}
+
+func (p *Postgres) RewrapExisting(ctx context.Context) (int, error) {
+	return rewrapExisting(ctx, p, p.Envelope)
+}
+
+func (p *Postgres) storedKeys(ctx context.Context) ([]storedKey, error) {
+	rows, err := p.Db.QueryContext(ctx, `SELECT id, data_key FROM tasks ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []storedKey
+	for rows.Next() {
+		var key storedKey
+		if err = rows.Scan(&key.ID, &key.DataKey); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (p *Postgres) AddDelegation(ctx context.Context, delegation model.Delegation) (model.Delegation, error) {
+	if delegation.ID.IsZero() {
+		delegation.ID = primitive.NewObjectID()
+	}
+	delegation.CreatedAt = now()
+
+	_, err := p.Db.ExecContext(ctx, `INSERT INTO delegations
+		(id, delegator, delegate, starts_at, ends_at, created_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+		delegation.ID.Hex(), delegation.Delegator, delegation.Delegate,
+		delegation.Start, delegation.End, delegation.CreatedAt)
+	if err != nil {
+		return model.Delegation{}, err
+	}
+
+	return delegation, nil
+}
+
+func (p *Postgres) GetDelegations(ctx context.Context, email string) ([]model.Delegation, error) {
+	rows, err := p.Db.QueryContext(ctx, `SELECT id, delegator, delegate, starts_at, ends_at, created_at
+		FROM delegations WHERE delegator = $1 OR delegate = $1 ORDER BY starts_at, id`, email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var delegations []model.Delegation
+	for rows.Next() {
+		var id string
+		var d model.Delegation
+		if err = rows.Scan(&id, &d.Delegator, &d.Delegate, &d.Start, &d.End, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		if d.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+			return nil, err
+		}
+		d.Start, d.End, d.CreatedAt = d.Start.UTC(), d.End.UTC(), d.CreatedAt.UTC()
+
+		delegations = append(delegations, d)
+	}
+
+	return delegations, rows.Err()
+}
+
+func (p *Postgres) DeleteDelegation(ctx context.Context, delegationId, delegator string) error {
+	if _, err := primitive.ObjectIDFromHex(delegationId); err != nil {
+		return err
+	}
+
+	res, err := p.Db.ExecContext(ctx, `DELETE FROM delegations WHERE id = $1 AND delegator = $2`,
+		delegationId, delegator)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrDelegationNotFound
+	}
+
+	return nil
+}
+
+// queryTasks loads tasks selected by query along with their history.
+// The query must select id, data_key, version, created_at, updated_at and document columns.
+func (p *Postgres) queryTasks(ctx context.Context, query string, args ...interface{}) ([]model.Task, error) {
+	rows, err := p.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []model.Task
+	var ids []string
+	for rows.Next() {
+		var id, dataKey string
+		var version int64
+		var createdAt, updatedAt time.Time
+		var document []byte
+		if err = rows.Scan(&id, &dataKey, &version, &createdAt, &updatedAt, &document); err != nil {
+			return nil, err
+		}
+
+		var task model.Task
+		if err = json.Unmarshal(document, &task); err != nil {
+			return nil, err
+		}
+		task.DataKey = dataKey
+		task.Version = version
+		task.CreatedAt = createdAt.UTC()
+		task.UpdatedAt = updatedAt.UTC()
+
+		tasks = append(tasks, task)
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err = p.loadHistory(ctx, tasks, ids); err != nil {
+		return nil, err
+	}
+
+	for i := range tasks {
+		if err = open(p.Envelope, &tasks[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return tasks, nil
+}
+
+func (p *Postgres) loadHistory(ctx context.Context, tasks []model.Task, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(ids))
+	for i, id := range ids {
+		index[id] = i
+	}
+
+	rows, err := p.Db.QueryContext(ctx, `SELECT task_id, event FROM task_events
+		WHERE task_id = ANY($1) ORDER BY task_id, seq`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var raw []byte
+		if err = rows.Scan(&id, &raw); err != nil {
+			return err
+		}
+
+		var event model.Event
+		if err = json.Unmarshal(raw, &event); err != nil {
+			return err
+		}
+
+		task := &tasks[index[id]]
+		task.History = append(task.History, event)
+	}
+
+	return rows.Err()
+}
+
+// writeCoordinators replaces coordinator rows of the task with its current stages and keeps
+// the rest of Task.Coordinators, such as substituted coordinators and those of previous revisions,
+// with stage -1 at their position in Task.Coordinators, so that lookups match those of MongoDB.
+func (p *Postgres) writeCoordinators(ctx context.Context, tx *sql.Tx, task model.Task) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM task_coordinators WHERE task_id = $1`, task.ID.Hex())
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]bool)
+	for i, stage := range task.Stages {
+		for j, email := range stage.Coordinators {
+			_, err = tx.ExecContext(ctx, `INSERT INTO task_coordinators (task_id, stage, position, email)
+				VALUES ($1, $2, $3, $4)`, task.ID.Hex(), i, j, email)
+			if err != nil {
+				return err
+			}
+			current[email] = true
+		}
+	}
+
+	for j, email := range task.Coordinators {
+		if current[email] {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO task_coordinators (task_id, stage, position, email)
+			VALUES ($1, -1, $2, $3)`, task.ID.Hex(), j, email)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeDecisions inserts decisions of the current revision which are not stored yet.
+// Stored decisions are never changed, including those of previous revisions.
+func (p *Postgres) writeDecisions(ctx context.Context, tx *sql.Tx, task model.Task) error {
+	for i, stage := range task.Stages {
+		for _, d := range stage.Decisions {
+			_, err := tx.ExecContext(ctx, `INSERT INTO task_decisions
+				(task_id, revision, stage, coordinator, approved, decided_at, content_hash, signature, decided_by, comment)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+				ON CONFLICT (task_id, revision, stage, coordinator) DO NOTHING`,
+				task.ID.Hex(), task.Number(), i, d.Coordinator, d.Approved, d.Time, d.ContentHash, d.Signature, d.DecidedBy,
+				d.Comment)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// appendEvents inserts events starting at sequence number from.
+func (p *Postgres) appendEvents(ctx context.Context, tx *sql.Tx, id primitive.ObjectID, from int, events []model.Event) error {
+	for i, e := range events {
+		raw, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO task_events (task_id, seq, hash, event)
+			VALUES ($1, $2, $3, $4)`, id.Hex(), from+i, e.Hash, string(raw))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Postgres) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// taskDocument returns JSON document of task without its history, which is stored separately.
+func taskDocument(task model.Task) ([]byte, error) {
+	task.History = nil
+
+	return json.Marshal(task)
+}
@@ -0,0 +1,286 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/richard-on/task-service/internal/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SortField is a task field which listed tasks can be ordered by.
+type SortField string
+
+const (
+	SortCreated SortField = "created_at"
+	SortUpdated SortField = "updated_at"
+	SortName    SortField = "name"
+	SortStatus  SortField = "status"
+)
+
+// Valid reports whether f is a known sort field. Empty field is treated as SortCreated.
+func (f SortField) Valid() bool {
+	switch f {
+	case "", SortCreated, SortUpdated, SortName, SortStatus:
+		return true
+	}
+
+	return false
+}
+
+const (
+	// DefaultLimit is the page size used when Query.Limit is not set.
+	DefaultLimit = 50
+	// MaxLimit is the largest allowed page size.
+	MaxLimit = 200
+)
+
+// cursorTime is a fixed width time layout, so that cursor values of time fields sort as strings.
+const cursorTime = "2006-01-02T15:04:05.000000000Z"
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+var ErrInvalidSort = errors.New("unknown sort field")
+
+// ErrEncryptedField is returned when tasks are filtered or sorted by name, which the database
+// can't see when encryption at rest is enabled.
+var ErrEncryptedField = errors.New("tasks can't be filtered or sorted by name when encryption at rest is enabled")
+
+// Query selects, orders and pages tasks. Zero values of filters match every task.
+type Query struct {
+	Initiator string
+	// Statuses matches tasks in any of the listed statuses.
+	Statuses    []model.Status
+	Coordinator string
+	// Created and updated ranges include From and exclude To.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	UpdatedFrom time.Time
+	UpdatedTo   time.Time
+	// Name matches tasks whose name contains it, ignoring case.
+	Name string
+
+	Sort SortField
+	Desc bool
+	// Limit is the page size, DefaultLimit if not set.
+	Limit int
+	// Cursor is Page.NextCursor of the previous page, empty for the first page.
+	Cursor string
+}
+
+// Page is a single page of tasks selected by Query.
+type Page struct {
+	Tasks []model.Task
+	// NextCursor continues the listing, it is empty on the last page.
+	NextCursor string
+	// Total is the number of tasks matching the query on all pages.
+	Total int64
+}
+
+// cursor is the position of the last task of a page: its sort value and ID.
+type cursor struct {
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// validate checks the query before it is used to build database queries.
+func (q Query) validate() error {
+	if !q.Sort.Valid() {
+		return ErrInvalidSort
+	}
+
+	return nil
+}
+
+func (q Query) sortField() SortField {
+	if q.Sort == "" {
+		return SortCreated
+	}
+
+	return q.Sort
+}
+
+func (q Query) limit() int {
+	if q.Limit <= 0 {
+		return DefaultLimit
+	} else if q.Limit > MaxLimit {
+		return MaxLimit
+	}
+
+	return q.Limit
+}
+
+// sortValue returns the value task is sorted by in the form it is stored in a cursor.
+func (q Query) sortValue(task model.Task) string {
+	switch q.sortField() {
+	case SortUpdated:
+		return task.UpdatedAt.UTC().Format(cursorTime)
+	case SortName:
+		return task.Name
+	case SortStatus:
+		return string(task.Status)
+
+	default:
+		return task.CreatedAt.UTC().Format(cursorTime)
+	}
+}
+
+// cursorValue converts sort value from a cursor to the type the sort field is stored as.
+func (q Query) cursorValue(value string) (interface{}, error) {
+	switch q.sortField() {
+	case SortCreated, SortUpdated:
+		t, err := time.Parse(cursorTime, value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+
+		return t, nil
+
+	default:
+		return value, nil
+	}
+}
+
+// cursor decodes Query.Cursor. It returns nil if listing starts from the first page.
+func (q Query) cursor() (*cursor, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+	if err = json.Unmarshal(raw, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if _, err = primitive.ObjectIDFromHex(c.ID); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if _, err = q.cursorValue(c.Value); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// nextCursor returns the cursor of the page following tasks, which holds one more task
+// than the page size if there is a next page.
+func (q Query) nextCursor(tasks []model.Task) ([]model.Task, string) {
+	if len(tasks) <= q.limit() {
+		return tasks, ""
+	}
+
+	tasks = tasks[:q.limit()]
+	last := tasks[len(tasks)-1]
+	raw, _ := json.Marshal(cursor{Value: q.sortValue(last), ID: last.ID.Hex()})
+
+	return tasks, base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// usesName reports whether the query needs to read task names.
+func (q Query) usesName() bool {
+	return q.Name != "" || q.sortField() == SortName
+}
+
+// match reports whether task passes the query filters.
+func (q Query) match(task model.Task) bool {
+	if q.Initiator != "" && task.Initiator != q.Initiator {
+		return false
+	}
+	if len(q.Statuses) > 0 && !containsStatus(q.Statuses, task.Status) {
+		return false
+	}
+	if q.Coordinator != "" && !containsString(task.Coordinators, q.Coordinator) {
+		return false
+	}
+	if !inRange(task.CreatedAt, q.CreatedFrom, q.CreatedTo) || !inRange(task.UpdatedAt, q.UpdatedFrom, q.UpdatedTo) {
+		return false
+	}
+	if q.Name != "" && !strings.Contains(strings.ToLower(task.Name), strings.ToLower(q.Name)) {
+		return false
+	}
+
+	return true
+}
+
+// pageOf selects a page from tasks, which are filtered and sorted in memory.
+// It is used by stores without query support.
+func (q Query) pageOf(tasks []model.Task) (Page, error) {
+	if err := q.validate(); err != nil {
+		return Page{}, err
+	}
+
+	after, err := q.cursor()
+	if err != nil {
+		return Page{}, err
+	}
+
+	matched := tasks[:0]
+	for _, task := range tasks {
+		if q.match(task) {
+			matched = append(matched, task)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return q.before(q.sortValue(matched[i]), matched[i].ID.Hex(), q.sortValue(matched[j]), matched[j].ID.Hex())
+	})
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(matched), func(i int) bool {
+			return q.before(after.Value, after.ID, q.sortValue(matched[i]), matched[i].ID.Hex())
+		})
+	}
+
+	end := start + q.limit() + 1
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	page, next := q.nextCursor(matched[start:end])
+
+	return Page{Tasks: page, NextCursor: next, Total: int64(len(matched))}, nil
+}
+
+// before reports whether task with sort value a and ID aID is listed before task with value b and ID bID.
+func (q Query) before(a, aID, b, bID string) bool {
+	if a == b {
+		a, b = aID, bID
+	}
+	if q.Desc {
+		return a > b
+	}
+
+	return a < b
+}
+
+func inRange(t, from, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+}
+
+func containsStatus(statuses []model.Status, status model.Status) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
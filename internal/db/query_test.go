@@ -0,0 +1,163 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/richard-on/task-service/internal/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testTasks returns tasks named a to e. Tasks b and c are created at the same time,
+// so that their order is decided by ID.
+func testTasks() []model.Task {
+	base := time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC)
+	offsets := []time.Duration{0, time.Hour, time.Hour, 2 * time.Hour, 3 * time.Hour}
+	statuses := []model.Status{model.NotStarted, model.Approved, model.NotStarted, model.Declined, model.NotStarted}
+
+	tasks := make([]model.Task, len(offsets))
+	for i := range tasks {
+		id := primitive.NilObjectID
+		id[11] = byte(i + 1)
+		tasks[i] = model.Task{
+			ID:        id,
+			Name:      string(rune('a' + i)),
+			Initiator: "initiator",
+			Status:    statuses[i],
+			CreatedAt: base.Add(offsets[i]),
+			UpdatedAt: base.Add(offsets[i]),
+		}
+	}
+
+	return tasks
+}
+
+// listAll follows cursors of query from the first page to the last and returns names of the listed tasks.
+func listAll(t *testing.T, query Query) []string {
+	t.Helper()
+
+	var names []string
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("listing does not end")
+		}
+
+		page, err := query.pageOf(testTasks())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Tasks) > query.limit() {
+			t.Fatalf("page of %v tasks, limit is %v", len(page.Tasks), query.limit())
+		}
+		for _, task := range page.Tasks {
+			names = append(names, task.Name)
+		}
+
+		if page.NextCursor == "" {
+			return names
+		}
+		query.Cursor = page.NextCursor
+	}
+}
+
+func TestQueryCursors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{
+			name:  "created ascending",
+			query: Query{Limit: 2},
+			want:  []string{"a", "b", "c", "d", "e"},
+		},
+		{
+			name:  "created descending",
+			query: Query{Limit: 2, Desc: true},
+			want:  []string{"e", "d", "c", "b", "a"},
+		},
+		{
+			name:  "single page",
+			query: Query{Limit: 5},
+			want:  []string{"a", "b", "c", "d", "e"},
+		},
+		{
+			name:  "status ties ordered by ID",
+			query: Query{Limit: 1, Sort: SortStatus},
+			want:  []string{"b", "d", "a", "c", "e"},
+		},
+		{
+			name:  "filtered",
+			query: Query{Limit: 1, Statuses: []model.Status{model.NotStarted}, Desc: true},
+			want:  []string{"e", "c", "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := listAll(t, tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("listed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryTotal(t *testing.T) {
+	page, err := Query{Limit: 1, CreatedFrom: testTasks()[1].CreatedAt}.pageOf(testTasks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if page.Total != 4 || len(page.Tasks) != 1 {
+		t.Errorf("page of %v tasks out of %v, want 1 out of 4", len(page.Tasks), page.Total)
+	}
+}
+
+func TestQueryInvalid(t *testing.T) {
+	encode := func(c cursor) string {
+		raw, _ := json.Marshal(c)
+		return base64.RawURLEncoding.EncodeToString(raw)
+	}
+	id := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name  string
+		query Query
+		want  error
+	}{
+		{name: "not base64", query: Query{Cursor: "%%%"}, want: ErrInvalidCursor},
+		{name: "not json", query: Query{Cursor: "bm90IGpzb24"}, want: ErrInvalidCursor},
+		{name: "invalid ID", query: Query{Cursor: encode(cursor{Value: "a", ID: "1"}), Sort: SortName}, want: ErrInvalidCursor},
+		{name: "name in a time ordered listing", query: Query{Cursor: encode(cursor{Value: "a", ID: id})}, want: ErrInvalidCursor},
+		{name: "unknown sort field", query: Query{Sort: "priority"}, want: ErrInvalidSort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.query.pageOf(testTasks()); err != tt.want {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryLimit(t *testing.T) {
+	tests := []struct {
+		limit int
+		want  int
+	}{
+		{limit: 0, want: DefaultLimit},
+		{limit: -1, want: DefaultLimit},
+		{limit: 10, want: 10},
+		{limit: MaxLimit + 1, want: MaxLimit},
+	}
+
+	for _, tt := range tests {
+		if got := (Query{Limit: tt.limit}).limit(); got != tt.want {
+			t.Errorf("limit(%v) = %v, want %v", tt.limit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"github.com/richard-on/task-service/internal/model"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultSearchLimit is the number of search results returned when no limit is given.
+const DefaultSearchLimit = 20
+
+// nameWeight is how much more a term found in the task name counts than one found in its description.
+const nameWeight = 3
+
+// SearchResult is a task found by full-text search along with its relevance.
+// Scores are only comparable within the results of a single search.
+type SearchResult struct {
+	Task  model.Task
+	Score float64
+}
+
+// SearchTerms splits text into distinct lower case words.
+func SearchTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	var terms []string
+	seen := make(map[string]bool)
+	for _, w := range words {
+		if !seen[w] {
+			seen[w] = true
+			terms = append(terms, w)
+		}
+	}
+
+	return terms
+}
+
+func searchLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultSearchLimit
+	} else if limit > MaxLimit {
+		return MaxLimit
+	}
+
+	return limit
+}
+
+// rank scores tasks by occurrences of terms in their name and description and returns
+// the best limit of them. It is used by stores without a text index, so tasks must be decrypted.
+func rank(tasks []model.Task, terms []string, limit int) []SearchResult {
+	var results []SearchResult
+	for _, task := range tasks {
+		name := strings.ToLower(task.Name)
+		description := strings.ToLower(task.Description)
+
+		var score float64
+		for _, term := range terms {
+			score += float64(nameWeight*strings.Count(name, term) + strings.Count(description, term))
+		}
+		if score > 0 {
+			results = append(results, SearchResult{Task: task, Score: score})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score == results[j].Score {
+			return results[i].Task.ID.Hex() > results[j].Task.ID.Hex()
+		}
+
+		return results[i].Score > results[j].Score
+	})
+
+	if len(results) > searchLimit(limit) {
+		results = results[:searchLimit(limit)]
+	}
+
+	return results
+}
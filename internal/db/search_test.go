@@ -0,0 +1,44 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/richard-on/task-service/internal/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSearchTerms(t *testing.T) {
+	got := SearchTerms("Supply contract: supply-chain, 2023!")
+	want := []string{"supply", "contract", "chain", "2023"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchTerms() = %v, want %v", got, want)
+	}
+}
+
+func TestRank(t *testing.T) {
+	task := func(name, description string) model.Task {
+		return model.Task{ID: primitive.NewObjectID(), Name: name, Description: description}
+	}
+	tasks := []model.Task{
+		task("Budget", "contract for the budget"),
+		task("Contract", "new supplier"),
+		task("Vacation", "two weeks"),
+		task("Report", "contract, contract and contract"),
+	}
+
+	results := rank(tasks, []string{"contract"}, 2)
+
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Task.Name)
+	}
+	// A match in the name counts as much as three matches in the description, ties go to the newest task.
+	if want := []string{"Report", "Contract"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ranked %v, want %v", names, want)
+	}
+	if results[0].Score != results[1].Score {
+		t.Errorf("scores %v and %v, want equal", results[0].Score, results[1].Score)
+	}
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/richard-on/task-service/internal/model"
+)
+
+// ErrNotFound is returned when the requested task does not exist.
+var ErrNotFound = errors.New("task not found")
+
+// ErrDelegationNotFound is returned when the requested delegation does not exist.
+var ErrDelegationNotFound = errors.New("delegation not found")
+
+// TaskStore persists tasks. Implementations must be safe for concurrent use
+// and must give up once ctx is done, returning its error.
+type TaskStore interface {
+	// AddTask stores a new task and returns it as stored, with creation time set.
+	AddTask(ctx context.Context, task model.Task) (model.Task, error)
+	// ListTasks returns a page of tasks selected by query.
+	ListTasks(ctx context.Context, query Query) (Page, error)
+	// SearchTasks returns at most limit tasks which email initiates or coordinates and whose
+	// name or description contain words of text, the most relevant first.
+	SearchTasks(ctx context.Context, email, text string, limit int) ([]SearchResult, error)
+	// GetAwaitingTasks returns tasks on which email is allowed to act right now.
+	GetAwaitingTasks(ctx context.Context, email string) ([]model.Task, error)
+	// GetDecidedTasks returns tasks on which email has made a decision in any stage.
+	GetDecidedTasks(ctx context.Context, email string) ([]model.Task, error)
+	// GetOverdueTasks returns tasks which are past their due date at now and still await decisions.
+	GetOverdueTasks(ctx context.Context, now time.Time) ([]model.Task, error)
+	// GetBreachedTasks returns tasks with a coordinator who has been waiting longer than the SLA of the current stage at now.
+	GetBreachedTasks(ctx context.Context, now time.Time) ([]model.Task, error)
+	// GetTaskById returns the task with the given hex ID or ErrNotFound.
+	GetTaskById(ctx context.Context, taskId string) (model.Task, error)
+	// UpdateTask stores task if it has not been modified since it was read, otherwise returns ErrConflict.
+	// Only events recorded since the task was read are appended to its history.
+	// On success task.Version is incremented and task.UpdatedAt is set.
+	UpdateTask(ctx context.Context, task *model.Task) error
+	// DeleteTask removes the task with the given hex ID or returns ErrNotFound.
+	DeleteTask(ctx context.Context, taskId string) error
+}
+
+// DelegationStore persists delegations of approval authority. Implementations must be safe
+// for concurrent use and must give up once ctx is done, returning its error.
+type DelegationStore interface {
+	// AddDelegation stores a new delegation and returns it as stored, with ID and creation time set.
+	AddDelegation(ctx context.Context, delegation model.Delegation) (model.Delegation, error)
+	// GetDelegations returns delegations in which email is the delegator or the delegate, ordered by start.
+	GetDelegations(ctx context.Context, email string) ([]model.Delegation, error)
+	// DeleteDelegation removes the delegation with the given hex ID made by delegator or returns ErrDelegationNotFound.
+	DeleteDelegation(ctx context.Context, delegationId, delegator string) error
+}
+
+// Store is implemented by every storage backend.
+type Store interface {
+	TaskStore
+	DelegationStore
+}
+
+// now returns the current time in the precision all backends store it with.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// sortDelegations orders delegations by start, as GetDelegations returns them.
+func sortDelegations(delegations []model.Delegation) {
+	sort.Slice(delegations, func(i, j int) bool {
+		if !delegations[i].Start.Equal(delegations[j].Start) {
+			return delegations[i].Start.Before(delegations[j].Start)
+		}
+		return delegations[i].ID.Hex() < delegations[j].ID.Hex()
+	})
+}
+
+// created sets creation and modification time of a new task.
+func created(task *model.Task) {
+	task.CreatedAt = now()
+	task.UpdatedAt = task.CreatedAt
+}
@@ -9,11 +9,32 @@ import (
 	"io"
 )
 
+var ErrInvalidKey = errors.New("encryption key is not valid base64")
+
+var ErrInvalidValue = errors.New("encrypted value is not valid")
+
 func EncryptQuery(value, key string) (string, error) {
-	keyDecoded, _ := base64.StdEncoding.DecodeString(key)
+	keyDecoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", ErrInvalidKey
+	}
+
+	return encrypt(value, keyDecoded)
+}
+
+func DecryptQuery(value, key string) (string, error) {
+	keyDecoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", ErrInvalidKey
+	}
+
+	return decrypt(value, keyDecoded)
+}
+
+func encrypt(value string, key []byte) (string, error) {
 	plaintext := []byte(value)
 
-	block, err := aes.NewCipher(keyDecoded)
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", err
 	}
@@ -33,11 +54,13 @@ func EncryptQuery(value, key string) (string, error) {
 	return base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
-func DecryptQuery(value, key string) (string, error) {
-	keyDecoded, _ := base64.StdEncoding.DecodeString(key)
-	enc, _ := base64.StdEncoding.DecodeString(value)
+func decrypt(value string, key []byte) (string, error) {
+	enc, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", ErrInvalidValue
+	}
 
-	block, err := aes.NewCipher(keyDecoded)
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", err
 	}
@@ -50,7 +73,7 @@ func DecryptQuery(value, key string) (string, error) {
 	nonceSize := gcm.NonceSize()
 
 	if len(enc) < nonceSize {
-		return "", errors.New("encrypted value is not valid")
+		return "", ErrInvalidValue
 	}
 
 	nonce, ciphertext := enc[:nonceSize], enc[nonceSize:]
@@ -0,0 +1,115 @@
+package token
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+)
+
+// dataKeySize is the size of AES-256 data keys.
+const dataKeySize = 32
+
+// Envelope encrypts values with per-document data keys, which are themselves
+// encrypted (wrapped) with the primary key of a Keyring. Rotating the keyring
+// only requires re-wrapping data keys, not re-encrypting the values.
+type Envelope struct {
+	keyring *Keyring
+}
+
+// NewEnvelope creates an Envelope which wraps data keys with keyring.
+func NewEnvelope(keyring *Keyring) *Envelope {
+	return &Envelope{keyring: keyring}
+}
+
+// Seal encrypts non-empty values in place. It reuses the data key wrapped in wrappedKey
+// or generates a new one if wrappedKey is empty, and returns the wrapped data key.
+// A data key wrapped with a key other than the primary one is re-wrapped with the primary key,
+// so that the old key can be retired once every document has been sealed again.
+func (e *Envelope) Seal(wrappedKey string, values ...*string) (string, error) {
+	var dataKey []byte
+	var err error
+
+	if wrappedKey == "" {
+		dataKey = make([]byte, dataKeySize)
+		if _, err = io.ReadFull(rand.Reader, dataKey); err != nil {
+			return "", err
+		}
+
+		wrappedKey, err = e.wrap(dataKey)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		dataKey, err = e.unwrap(wrappedKey)
+		if err != nil {
+			return "", err
+		}
+
+		if e.Stale(wrappedKey) {
+			wrappedKey, err = e.wrap(dataKey)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	for _, v := range values {
+		// Empty values are left as they are, so that optional fields stay empty.
+		if *v == "" {
+			continue
+		}
+		enc, err := encrypt(*v, dataKey)
+		if err != nil {
+			return "", err
+		}
+		*v = enc
+	}
+
+	return wrappedKey, nil
+}
+
+// Open decrypts values sealed with the data key wrapped in wrappedKey in place.
+func (e *Envelope) Open(wrappedKey string, values ...*string) error {
+	dataKey, err := e.unwrap(wrappedKey)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range values {
+		if *v == "" {
+			continue
+		}
+		plaintext, err := decrypt(*v, dataKey)
+		if err != nil {
+			return err
+		}
+		*v = plaintext
+	}
+
+	return nil
+}
+
+// Stale reports whether wrappedKey is wrapped with a key other than the primary key.
+func (e *Envelope) Stale(wrappedKey string) bool {
+	primary, err := e.keyring.Primary()
+
+	return err == nil && KeyID(wrappedKey) != primary.ID
+}
+
+func (e *Envelope) wrap(dataKey []byte) (string, error) {
+	return e.keyring.Encrypt(base64.StdEncoding.EncodeToString(dataKey))
+}
+
+func (e *Envelope) unwrap(wrappedKey string) ([]byte, error) {
+	encoded, err := e.keyring.Decrypt(wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	return dataKey, nil
+}
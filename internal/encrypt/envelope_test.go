@@ -0,0 +1,114 @@
+package token
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestEnvelope(t *testing.T) {
+	keyring, err := NewKeyring(Key{ID: "v1", Secret: bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope := NewEnvelope(keyring)
+
+	name, description := "contract", "draft"
+	wrapped, err := envelope.Seal("", &name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name == "contract" {
+		t.Fatal("Seal() left the value in plaintext")
+	}
+
+	// Sealing more values with the same wrapped key keeps a single data key per document.
+	again, err := envelope.Seal(wrapped, &description)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != wrapped {
+		t.Errorf("Seal() rewrapped the data key: %q, want %q", again, wrapped)
+	}
+
+	if err = envelope.Open(wrapped, &name, &description); err != nil {
+		t.Fatal(err)
+	}
+	if name != "contract" || description != "draft" {
+		t.Errorf("Open() = %q, %q", name, description)
+	}
+
+	// Empty values stay empty, so that optional fields need no ciphertext.
+	empty := ""
+	if _, err = envelope.Seal(wrapped, &empty); err != nil || empty != "" {
+		t.Errorf("Seal() of an empty value = %q, %v", empty, err)
+	}
+	if err = envelope.Open(wrapped, &empty); err != nil || empty != "" {
+		t.Errorf("Open() of an empty value = %q, %v", empty, err)
+	}
+
+	other, err := NewKeyring(Key{ID: "v1", Secret: bytes.Repeat([]byte{2}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := "secret"
+	if wrapped, err = envelope.Seal("", &sealed); err != nil {
+		t.Fatal(err)
+	}
+	if err = NewEnvelope(other).Open(wrapped, &sealed); err == nil {
+		t.Error("Open() with a different keyring succeeded")
+	}
+}
+
+func TestEnvelopeRewrap(t *testing.T) {
+	rotation := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	keyring, err := NewKeyring(
+		Key{ID: "v1", Secret: bytes.Repeat([]byte{1}, 32)},
+		Key{ID: "v2", Secret: bytes.Repeat([]byte{2}, 32), NotBefore: rotation},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope := NewEnvelope(keyring)
+
+	keyring.now = func() time.Time { return rotation.Add(-time.Hour) }
+	name := "contract"
+	wrapped, err := envelope.Seal("", &name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Stale(wrapped) {
+		t.Fatal("freshly wrapped key is stale")
+	}
+
+	keyring.now = func() time.Time { return rotation }
+	if !envelope.Stale(wrapped) {
+		t.Fatal("key wrapped before rotation is not stale")
+	}
+
+	description := "draft"
+	rewrapped, err := envelope.Seal(wrapped, &description)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if KeyID(rewrapped) != "v2" || envelope.Stale(rewrapped) {
+		t.Fatalf("Seal() wrapped data key with %q, want v2", KeyID(rewrapped))
+	}
+
+	// Values sealed with the old wrapping share the data key, so they open with the new one.
+	if err = envelope.Open(rewrapped, &name, &description); err != nil {
+		t.Fatal(err)
+	}
+	if name != "contract" || description != "draft" {
+		t.Errorf("Open() = %q, %q", name, description)
+	}
+
+	// Empty values stay empty, so that optional fields need no ciphertext.
+	empty := ""
+	if _, err = envelope.Seal(wrapped, &empty); err != nil || empty != "" {
+		t.Errorf("Seal() of an empty value = %q, %v", empty, err)
+	}
+	if err = envelope.Open(wrapped, &empty); err != nil || empty != "" {
+		t.Errorf("Open() of an empty value = %q, %v", empty, err)
+	}
+}
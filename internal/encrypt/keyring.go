@@ -0,0 +1,172 @@
+package token
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrNoPrimaryKey = errors.New("keyring has no active key to encrypt with")
+
+var ErrUnknownKey = errors.New("value is encrypted with an unknown or retired key")
+
+// Key is a versioned AES key. A key becomes primary at NotBefore and
+// stops being accepted for decryption at NotAfter, if set.
+type Key struct {
+	ID        string
+	Secret    []byte
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// Active reports whether the key is accepted for decryption at now.
+func (k Key) Active(now time.Time) bool {
+	return k.NotAfter.IsZero() || now.Before(k.NotAfter)
+}
+
+// Keyring holds versioned keys. Values are encrypted with the primary key and prefixed with its ID,
+// so they can be decrypted after the primary key is rotated as long as their key is still active.
+type Keyring struct {
+	keys []Key
+	now  func() time.Time
+}
+
+// NewKeyring creates a Keyring from keys.
+func NewKeyring(keys ...Key) (*Keyring, error) {
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if k.ID == "" || strings.ContainsAny(k.ID, ".:,@") {
+			return nil, fmt.Errorf("invalid key id %q", k.ID)
+		}
+		if seen[k.ID] {
+			return nil, fmt.Errorf("duplicate key id %q", k.ID)
+		}
+		switch len(k.Secret) {
+		case 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("key %q must be 16, 24 or 32 bytes long", k.ID)
+		}
+		seen[k.ID] = true
+	}
+
+	sorted := append([]Key(nil), keys...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].NotBefore.Before(sorted[j].NotBefore)
+	})
+
+	return &Keyring{keys: sorted, now: time.Now}, nil
+}
+
+// ParseKeyring creates a Keyring from a comma separated list of keys in form
+// id:base64key[@notBefore[@notAfter]], where dates are RFC 3339 timestamps and either may be empty.
+// This lets a rotation schedule be set up in advance: a new key takes over
+// encryption at its notBefore and an old one is retired at its notAfter.
+func ParseKeyring(spec string) (*Keyring, error) {
+	var keys []Key
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, rest, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("key entry %q must be in form id:key[@notBefore[@notAfter]]", entry)
+		}
+		fields := strings.Split(rest, "@")
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("key entry %q must be in form id:key[@notBefore[@notAfter]]", entry)
+		}
+
+		secret, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, ErrInvalidKey)
+		}
+
+		key := Key{ID: id, Secret: secret}
+		if len(fields) > 1 && fields[1] != "" {
+			if key.NotBefore, err = time.Parse(time.RFC3339, fields[1]); err != nil {
+				return nil, fmt.Errorf("key %q: %w", id, err)
+			}
+		}
+		if len(fields) > 2 && fields[2] != "" {
+			if key.NotAfter, err = time.Parse(time.RFC3339, fields[2]); err != nil {
+				return nil, fmt.Errorf("key %q: %w", id, err)
+			}
+		}
+
+		keys = append(keys, key)
+	}
+
+	return NewKeyring(keys...)
+}
+
+// Primary returns the key used for encryption: the active key with the latest NotBefore in the past.
+func (k *Keyring) Primary() (Key, error) {
+	now := k.now()
+
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		key := k.keys[i]
+		if !key.NotBefore.After(now) && key.Active(now) {
+			return key, nil
+		}
+	}
+
+	return Key{}, ErrNoPrimaryKey
+}
+
+// Encrypt encrypts value with the primary key and prefixes the result with the key ID.
+func (k *Keyring) Encrypt(value string) (string, error) {
+	key, err := k.Primary()
+	if err != nil {
+		return "", err
+	}
+
+	enc, err := encrypt(value, key.Secret)
+	if err != nil {
+		return "", err
+	}
+
+	return key.ID + "." + enc, nil
+}
+
+// KeyID returns the ID of the key value was encrypted with by Encrypt or "" if value has no key ID.
+func KeyID(value string) string {
+	id, _, found := strings.Cut(value, ".")
+	if !found {
+		return ""
+	}
+
+	return id
+}
+
+// Decrypt decrypts value with the active key whose ID value is prefixed with.
+// Values without a key ID, produced by EncryptQuery, are tried against every active key.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	now := k.now()
+
+	id, enc, found := strings.Cut(value, ".")
+	if !found {
+		for _, key := range k.keys {
+			if !key.Active(now) {
+				continue
+			}
+			if plaintext, err := decrypt(value, key.Secret); err == nil {
+				return plaintext, nil
+			}
+		}
+
+		return "", ErrUnknownKey
+	}
+
+	for _, key := range k.keys {
+		if key.ID == id && key.Active(now) {
+			return decrypt(enc, key.Secret)
+		}
+	}
+
+	return "", ErrUnknownKey
+}
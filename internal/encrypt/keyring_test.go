@@ -0,0 +1,109 @@
+package token
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyringRotation(t *testing.T) {
+	rotation := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	retirement := rotation.Add(30 * 24 * time.Hour)
+
+	keyring, err := NewKeyring(
+		Key{ID: "v2", Secret: bytes.Repeat([]byte{2}, 32), NotBefore: rotation},
+		Key{ID: "v1", Secret: bytes.Repeat([]byte{1}, 32), NotAfter: retirement},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyring.now = func() time.Time { return rotation.Add(-time.Hour) }
+	old, err := keyring.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if KeyID(old) != "v1" {
+		t.Fatalf("encrypted with %q before rotation, want v1", KeyID(old))
+	}
+
+	keyring.now = func() time.Time { return rotation }
+	current, err := keyring.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if KeyID(current) != "v2" {
+		t.Fatalf("encrypted with %q after rotation, want v2", KeyID(current))
+	}
+	for _, value := range []string{old, current} {
+		if plaintext, err := keyring.Decrypt(value); err != nil || plaintext != "secret" {
+			t.Errorf("Decrypt(%q) = %q, %v before retirement", value, plaintext, err)
+		}
+	}
+
+	keyring.now = func() time.Time { return retirement }
+	if _, err = keyring.Decrypt(old); err != ErrUnknownKey {
+		t.Errorf("Decrypt with a retired key: err = %v, want %v", err, ErrUnknownKey)
+	}
+	if plaintext, err := keyring.Decrypt(current); err != nil || plaintext != "secret" {
+		t.Errorf("Decrypt(%q) = %q, %v after retirement", current, plaintext, err)
+	}
+}
+
+func TestKeyringNoPrimary(t *testing.T) {
+	keyring, err := NewKeyring(Key{ID: "v1", Secret: bytes.Repeat([]byte{1}, 16),
+		NotBefore: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = keyring.Encrypt("secret"); err != ErrNoPrimaryKey {
+		t.Errorf("err = %v, want %v", err, ErrNoPrimaryKey)
+	}
+}
+
+func TestParseKeyring(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{spec: "v1:" + secret},
+		{spec: "v1:" + secret + "@2023-01-01T00:00:00Z, v2:" + secret + "@@2024-01-01T00:00:00Z"},
+		{spec: "v1", wantErr: true},
+		{spec: "v1:not-base64", wantErr: true},
+		{spec: "v1:" + base64.StdEncoding.EncodeToString([]byte("short")), wantErr: true},
+		{spec: "v1:" + secret + ",v1:" + secret, wantErr: true},
+		{spec: "v.1:" + secret, wantErr: true},
+		{spec: "v1:" + secret + "@yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if _, err := ParseKeyring(tt.spec); (err != nil) != tt.wantErr {
+			t.Errorf("ParseKeyring(%q): err = %v, want error %v", tt.spec, err, tt.wantErr)
+		}
+	}
+}
+
+func TestKeyringDecryptQuery(t *testing.T) {
+	secret := bytes.Repeat([]byte{1}, 32)
+	keyring, err := NewKeyring(Key{ID: "action", Secret: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Values produced by EncryptQuery carry no key ID.
+	value, err := EncryptQuery("secret", base64.StdEncoding.EncodeToString(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(value, ".") {
+		t.Fatalf("EncryptQuery() = %q contains a key ID separator", value)
+	}
+	if plaintext, err := keyring.Decrypt(value); err != nil || plaintext != "secret" {
+		t.Errorf("Decrypt() = %q, %v", plaintext, err)
+	}
+}
@@ -0,0 +1,280 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrDuplicateCoordinator = errors.New("coordinator is listed more than once in the stage")
+
+var ErrNotActive = errors.New("coordinator is not allowed to act on this task now")
+
+var ErrOverdue = errors.New("task is past its due date")
+
+// Required returns the number of approvals needed to complete the stage.
+func (s *Stage) Required() int {
+	if s.Mode == Quorum {
+		return s.Quorum
+	}
+
+	return len(s.Coordinators)
+}
+
+// Approvals returns the number of approvals the stage has received.
+func (s *Stage) Approvals() int {
+	var n int
+	for _, d := range s.Decisions {
+		if d.Approved {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Done reports whether the stage has received enough approvals.
+func (s *Stage) Done() bool {
+	return s.Approvals() >= s.Required()
+}
+
+// Failed reports whether the stage can no longer be completed. In Quorum mode this happens
+// only when the quorum becomes unreachable, in other modes any refusal fails the stage.
+func (s *Stage) Failed() bool {
+	if s.Mode == Quorum {
+		return s.Approvals()+len(s.undecided()) < s.Required()
+	}
+
+	return s.Approvals() < len(s.Decisions)
+}
+
+// Active returns coordinators who are allowed to act on the stage right now.
+func (s *Stage) Active() []string {
+	if s.Done() || s.Failed() {
+		return nil
+	}
+
+	if s.Mode == "" || s.Mode == Sequential {
+		if s.Next < len(s.Coordinators) {
+			return []string{s.Coordinators[s.Next]}
+		}
+		return nil
+	}
+
+	return s.undecided()
+}
+
+// Approve records coordinator's approval with an optional comment. Caller must check that coordinator is active.
+func (s *Stage) Approve(coordinator, comment string) {
+	s.Decisions = append(s.Decisions, Decision{Coordinator: coordinator, Approved: true, Comment: comment})
+
+	if (s.Mode == "" || s.Mode == Sequential) && s.Next+1 < len(s.Coordinators) {
+		s.Next = s.Next + 1
+	}
+}
+
+// Decline records coordinator's refusal with an optional reason. Caller must check that coordinator is active.
+func (s *Stage) Decline(coordinator, reason string) {
+	s.Decisions = append(s.Decisions, Decision{Coordinator: coordinator, Approved: false, Comment: reason})
+}
+
+// Decision returns the decision of coordinator or nil if coordinator has not decided yet.
+func (s *Stage) Decision(coordinator string) *Decision {
+	for i := range s.Decisions {
+		if s.Decisions[i].Coordinator == coordinator {
+			return &s.Decisions[i]
+		}
+	}
+
+	return nil
+}
+
+func (s *Stage) undecided() []string {
+	var undecided []string
+	for _, c := range s.Coordinators {
+		if !s.decided(c) {
+			undecided = append(undecided, c)
+		}
+	}
+
+	return undecided
+}
+
+func (s *Stage) decided(coordinator string) bool {
+	return s.Decision(coordinator) != nil
+}
+
+// Finished reports whether the task has reached a final status.
+func (t *Task) Finished() bool {
+	return t.Status.Final()
+}
+
+// Current returns the stage awaiting decisions or nil if there is none.
+func (t *Task) Current() *Stage {
+	if t.Stage < 0 || t.Stage >= len(t.Stages) {
+		return nil
+	}
+
+	return &t.Stages[t.Stage]
+}
+
+// Active returns coordinators who are allowed to act on the task right now.
+func (t *Task) Active() []string {
+	stage := t.Current()
+	if (t.Status != NotStarted && t.Status != InProgress) || stage == nil {
+		return nil
+	}
+
+	return stage.Active()
+}
+
+// CanAct reports whether coordinator is allowed to act on the task right now.
+func (t *Task) CanAct(coordinator string) bool {
+	for _, c := range t.Active() {
+		if c == coordinator {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Overdue reports whether the task is past its due date at now and still awaits decisions.
+func (t *Task) Overdue(now time.Time) bool {
+	return t.DueAt != nil && !now.Before(*t.DueAt) && t.Status.CanTransition(Expired)
+}
+
+// Expire moves an overdue task to Expired and records it in the task history.
+func (t *Task) Expire(now time.Time) error {
+	if !t.Overdue(now) {
+		return transitionError(t.Status, Expired)
+	}
+	if err := t.Transition(Expired); err != nil {
+		return err
+	}
+
+	return t.Record(Event{Actor: SystemActor, Action: ActionExpired, Stage: t.Stage, Time: now})
+}
+
+// Withdraw moves the task to Withdrawn on behalf of its initiator. Stages and history are kept.
+func (t *Task) Withdraw() error {
+	return t.Transition(Withdrawn)
+}
+
+// Reached returns coordinators who have been asked to decide on the task so far, that is
+// coordinators of the stages before the current one and those of the current stage
+// who have become active.
+func (t *Task) Reached() []string {
+	var reached []string
+	for i := 0; i <= t.Stage && i < len(t.Stages); i++ {
+		stage := t.Stages[i]
+		coordinators := stage.Coordinators
+		if i == t.Stage && (stage.Mode == "" || stage.Mode == Sequential) && stage.Next+1 < len(coordinators) {
+			coordinators = coordinators[:stage.Next+1]
+		}
+
+		for _, c := range coordinators {
+			if !contains(reached, c) {
+				reached = append(reached, c)
+			}
+		}
+	}
+
+	return reached
+}
+
+// Decided reports whether coordinator has made a decision on any stage of any revision
+// of the task, either their own or on behalf of someone else.
+func (t *Task) Decided(coordinator string) bool {
+	for _, r := range t.AllRevisions() {
+		for i := range r.Stages {
+			if r.Stages[i].decided(coordinator) {
+				return true
+			}
+			for _, d := range r.Stages[i].Decisions {
+				if d.DecidedBy == coordinator {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// Approve records coordinator's approval in the current stage and moves the task
+// to the next stage once the current one is complete.
+func (t *Task) Approve(coordinator, comment string) error {
+	stage, err := t.begin(coordinator)
+	if err != nil {
+		return err
+	}
+	stage.Approve(coordinator, comment)
+
+	return t.advance()
+}
+
+// advance moves the task to the next stage or approves it once the current stage is complete.
+func (t *Task) advance() error {
+	stage := t.Current()
+
+	switch {
+	case !stage.Done():
+		return nil
+	case t.Stage+1 < len(t.Stages):
+		t.Stage = t.Stage + 1
+		return nil
+	default:
+		return t.Transition(Approved)
+	}
+}
+
+// Decline records coordinator's refusal in the current stage and declines the task
+// once the stage can no longer be completed.
+func (t *Task) Decline(coordinator, reason string) error {
+	stage, err := t.begin(coordinator)
+	if err != nil {
+		return err
+	}
+	stage.Decline(coordinator, reason)
+
+	if stage.Failed() {
+		return t.Transition(Declined)
+	}
+
+	return nil
+}
+
+// Return hands the task back to its initiator on behalf of coordinator, who asks for changes
+// instead of deciding. Decisions made so far are kept, so the task continues where it stopped once resumed.
+func (t *Task) Return(coordinator string) error {
+	if _, err := t.begin(coordinator); err != nil {
+		return err
+	}
+
+	return t.Transition(Returned)
+}
+
+// Resume moves a returned task back in progress on behalf of its initiator.
+func (t *Task) Resume() error {
+	if t.Status != Returned {
+		return transitionError(t.Status, InProgress)
+	}
+
+	return t.Transition(InProgress)
+}
+
+// begin checks that coordinator may decide on the task now and moves the task in progress.
+func (t *Task) begin(coordinator string) (*Stage, error) {
+	if !t.Status.CanTransition(InProgress) {
+		return nil, transitionError(t.Status, InProgress)
+	}
+	if !t.CanAct(coordinator) {
+		return nil, ErrNotActive
+	}
+	// Task may not have been expired yet, but decisions are no longer accepted.
+	if t.Overdue(time.Now()) {
+		return nil, ErrOverdue
+	}
+
+	return t.Current(), t.Transition(InProgress)
+}
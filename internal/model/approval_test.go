@@ -0,0 +1,229 @@
+package model
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStageDoneFailed(t *testing.T) {
+	approve := func(c string) Decision { return Decision{Coordinator: c, Approved: true} }
+	decline := func(c string) Decision { return Decision{Coordinator: c} }
+
+	tests := []struct {
+		name      string
+		stage     Stage
+		done      bool
+		failed    bool
+		remaining []string
+	}{
+		{
+			name:      "sequential waits for the first coordinator",
+			stage:     Stage{Coordinators: []string{"a", "b"}, Mode: Sequential},
+			remaining: []string{"a"},
+		},
+		{
+			name:      "sequential waits for the next coordinator",
+			stage:     Stage{Coordinators: []string{"a", "b"}, Mode: Sequential, Next: 1, Decisions: []Decision{approve("a")}},
+			remaining: []string{"b"},
+		},
+		{
+			name:  "sequential is done once everyone approves",
+			stage: Stage{Coordinators: []string{"a", "b"}, Mode: Sequential, Next: 1, Decisions: []Decision{approve("a"), approve("b")}},
+			done:  true,
+		},
+		{
+			name:   "empty mode fails on the first refusal",
+			stage:  Stage{Coordinators: []string{"a", "b"}, Decisions: []Decision{decline("a")}},
+			failed: true,
+		},
+		{
+			name:      "parallel waits for the rest in any order",
+			stage:     Stage{Coordinators: []string{"a", "b", "c"}, Mode: Parallel, Decisions: []Decision{approve("b")}},
+			remaining: []string{"a", "c"},
+		},
+		{
+			name:   "parallel fails on any refusal",
+			stage:  Stage{Coordinators: []string{"a", "b", "c"}, Mode: Parallel, Decisions: []Decision{approve("a"), decline("c")}},
+			failed: true,
+		},
+		{
+			name:  "quorum is done once reached",
+			stage: Stage{Coordinators: []string{"a", "b", "c"}, Mode: Quorum, Quorum: 2, Decisions: []Decision{approve("a"), approve("c")}},
+			done:  true,
+		},
+		{
+			name:      "quorum survives a refusal while still reachable",
+			stage:     Stage{Coordinators: []string{"a", "b", "c"}, Mode: Quorum, Quorum: 2, Decisions: []Decision{decline("a")}},
+			remaining: []string{"b", "c"},
+		},
+		{
+			name:   "quorum fails once unreachable",
+			stage:  Stage{Coordinators: []string{"a", "b", "c"}, Mode: Quorum, Quorum: 2, Decisions: []Decision{decline("a"), decline("b")}},
+			failed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.stage.Done(); got != tt.done {
+				t.Errorf("Done() = %v, want %v", got, tt.done)
+			}
+			if got := tt.stage.Failed(); got != tt.failed {
+				t.Errorf("Failed() = %v, want %v", got, tt.failed)
+			}
+			if got := tt.stage.Active(); !reflect.DeepEqual(got, tt.remaining) {
+				t.Errorf("Active() = %v, want %v", got, tt.remaining)
+			}
+		})
+	}
+}
+
+func TestTaskApproveStages(t *testing.T) {
+	task := Task{
+		Status: NotStarted,
+		Stages: []Stage{
+			{Coordinators: []string{"a", "b"}, Mode: Sequential},
+			{Coordinators: []string{"c", "d", "e"}, Mode: Quorum, Quorum: 2},
+		},
+	}
+
+	if err := task.Approve("b", ""); err != ErrNotActive {
+		t.Fatalf("Approve out of turn: err = %v, want %v", err, ErrNotActive)
+	}
+
+	for _, c := range []string{"a", "b", "d"} {
+		if err := task.Approve(c, ""); err != nil {
+			t.Fatalf("Approve(%q): %v", c, err)
+		}
+	}
+	if task.Stage != 1 || task.Status != InProgress {
+		t.Fatalf("stage %v, status %v, want stage 1 in progress", task.Stage, task.Status)
+	}
+
+	if err := task.Decline("c", ""); err != nil {
+		t.Fatalf("Decline: %v", err)
+	}
+	if task.Status != InProgress {
+		t.Fatalf("status = %v after a refusal within quorum, want %v", task.Status, InProgress)
+	}
+
+	if err := task.Approve("e", ""); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	if task.Status != Approved {
+		t.Fatalf("status = %v, want %v", task.Status, Approved)
+	}
+	if err := task.Approve("c", ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Approve on an approved task: err = %v, want %v", err, ErrInvalidTransition)
+	}
+}
+
+func TestTaskDeclineQuorumUnreachable(t *testing.T) {
+	task := Task{
+		Status: NotStarted,
+		Stages: []Stage{{Coordinators: []string{"a", "b", "c"}, Mode: Quorum, Quorum: 2}},
+	}
+
+	if err := task.Decline("a", ""); err != nil {
+		t.Fatalf("Decline(a): %v", err)
+	}
+	if err := task.Decline("b", ""); err != nil {
+		t.Fatalf("Decline(b): %v", err)
+	}
+	if task.Status != Declined {
+		t.Fatalf("status = %v, want %v", task.Status, Declined)
+	}
+	if !task.Finished() {
+		t.Error("declined task is not finished")
+	}
+}
+
+func TestTaskReturnResume(t *testing.T) {
+	task := Task{
+		Status: NotStarted,
+		Stages: []Stage{{Coordinators: []string{"a", "b", "c"}, Mode: Sequential}},
+	}
+
+	if err := task.Resume(); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Resume of a task which is not returned: err = %v, want %v", err, ErrInvalidTransition)
+	}
+	if err := task.Approve("a", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := task.Return("c"); err != ErrNotActive {
+		t.Fatalf("Return out of turn: err = %v, want %v", err, ErrNotActive)
+	}
+	if err := task.Return("b"); err != nil {
+		t.Fatal(err)
+	}
+	if task.Status != Returned || task.Active() != nil {
+		t.Fatalf("status %v, active %v, want returned with nobody to act", task.Status, task.Active())
+	}
+	if err := task.Approve("b", ""); err != ErrNotActive {
+		t.Fatalf("Approve of a returned task: err = %v, want %v", err, ErrNotActive)
+	}
+
+	// The task continues where it was returned, keeping decisions made before.
+	if err := task.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if task.Status != InProgress || !task.CanAct("b") || task.Current().Approvals() != 1 {
+		t.Fatalf("status %v, active %v, stage %+v, want b to decide next", task.Status, task.Active(), task.Current())
+	}
+	if err := task.Resume(); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Resume of a resumed task: err = %v, want %v", err, ErrInvalidTransition)
+	}
+}
+
+func TestTaskExpire(t *testing.T) {
+	now := time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC)
+	due := now.Add(-time.Minute)
+
+	task := Task{Status: InProgress, DueAt: &due, Stages: []Stage{{Coordinators: []string{"a"}}}}
+	if err := task.Approve("a", ""); err != ErrOverdue {
+		t.Fatalf("Approve after the due date: err = %v, want %v", err, ErrOverdue)
+	}
+	if task.Overdue(due.Add(-time.Second)) {
+		t.Error("task is overdue before its due date")
+	}
+	if err := task.Expire(now); err != nil {
+		t.Fatal(err)
+	}
+	if task.Status != Expired || len(task.History) != 1 || task.History[0].Action != ActionExpired {
+		t.Fatalf("status %v, history %+v, want expired", task.Status, task.History)
+	}
+	if err := task.Expire(now); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expire of an expired task: err = %v, want %v", err, ErrInvalidTransition)
+	}
+}
+
+func TestTaskReached(t *testing.T) {
+	stages := func(mode Mode, next int) []Stage {
+		return []Stage{
+			{Coordinators: []string{"a"}},
+			{Coordinators: []string{"b", "c", "d"}, Mode: mode, Next: next},
+			{Coordinators: []string{"e"}},
+		}
+	}
+
+	tests := []struct {
+		name string
+		task Task
+		want []string
+	}{
+		{name: "first stage", task: Task{Stages: stages(Sequential, 0)}, want: []string{"a"}},
+		{name: "sequential turn", task: Task{Stage: 1, Stages: stages(Sequential, 1)}, want: []string{"a", "b", "c"}},
+		{name: "parallel stage", task: Task{Stage: 1, Stages: stages(Parallel, 0)}, want: []string{"a", "b", "c", "d"}},
+		{name: "last stage", task: Task{Stage: 2, Stages: stages(Sequential, 3)}, want: []string{"a", "b", "c", "d", "e"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.task.Reached(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Reached() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Delegation hands approval authority of Delegator over to Delegate from Start until End,
+// for example while Delegator is on vacation. Delegate decides on behalf of Delegator
+// and receives coordination emails addressed to Delegator.
+type Delegation struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Delegator string             `json:"delegator" bson:"delegator"`
+	Delegate  string             `json:"delegate" bson:"delegate"`
+	Start     time.Time          `json:"start" bson:"start"`
+	End       time.Time          `json:"end" bson:"end"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// Active reports whether the delegation is in effect at now.
+func (d Delegation) Active(now time.Time) bool {
+	return !now.Before(d.Start) && now.Before(d.End)
+}
+
+// Overlaps reports whether both delegations are in effect at some moment.
+func (d Delegation) Overlaps(other Delegation) bool {
+	return d.Start.Before(other.End) && other.Start.Before(d.End)
+}
+
+// ActiveDelegate returns the delegate of delegator among delegations at now or "" if there is none.
+// Delegations of the same delegator never overlap, so there is at most one.
+func ActiveDelegate(delegations []Delegation, delegator string, now time.Time) string {
+	for _, d := range delegations {
+		if d.Delegator == delegator && d.Active(now) {
+			return d.Delegate
+		}
+	}
+
+	return ""
+}
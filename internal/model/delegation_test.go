@@ -0,0 +1,44 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActiveDelegate(t *testing.T) {
+	start := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	delegations := []Delegation{
+		{Delegator: "a", Delegate: "d", Start: start, End: start.Add(24 * time.Hour)},
+		{Delegator: "a", Delegate: "e", Start: start.Add(24 * time.Hour), End: start.Add(48 * time.Hour)},
+		{Delegator: "b", Delegate: "a", Start: start, End: start.Add(48 * time.Hour)},
+	}
+
+	tests := []struct {
+		name      string
+		delegator string
+		at        time.Time
+		want      string
+	}{
+		{name: "before start", delegator: "a", at: start.Add(-time.Second)},
+		{name: "at start", delegator: "a", at: start, want: "d"},
+		{name: "handed over at end", delegator: "a", at: start.Add(24 * time.Hour), want: "e"},
+		{name: "after end", delegator: "a", at: start.Add(48 * time.Hour)},
+		{name: "delegate does not pass authority on", delegator: "d", at: start},
+		{name: "other delegator", delegator: "b", at: start, want: "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ActiveDelegate(delegations, tt.delegator, tt.at); got != tt.want {
+				t.Errorf("ActiveDelegate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if delegations[0].Overlaps(delegations[1]) {
+		t.Error("consecutive delegations overlap")
+	}
+	if !delegations[0].Overlaps(delegations[2]) {
+		t.Error("concurrent delegations don't overlap")
+	}
+}
@@ -0,0 +1,214 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// EscalationPolicy defines what happens when coordinators of a stage keep it waiting longer than its SLA.
+type EscalationPolicy string
+
+const (
+	// EscalateNotify tells the escalation contact who the task is waiting for.
+	EscalateNotify EscalationPolicy = "notify"
+	// EscalateDelegate hands the stalled step over to the escalation contact.
+	EscalateDelegate EscalationPolicy = "delegate"
+	// EscalateSkip removes stalled coordinators from the stage, so that it can complete without them.
+	// A stage which can no longer be completed after the skip, or would be completed without
+	// a single approval, declines the task.
+	EscalateSkip EscalationPolicy = "skip"
+)
+
+var ErrNotBreached = errors.New("task is within its SLA")
+
+// Valid reports whether p is a known escalation policy. Empty policy is treated as EscalateNotify.
+func (p EscalationPolicy) Valid() bool {
+	switch p {
+	case "", EscalateNotify, EscalateDelegate, EscalateSkip:
+		return true
+	}
+
+	return false
+}
+
+// Duration is a time.Duration represented in JSON as a string, such as "48h".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+
+	return nil
+}
+
+// ActiveSince returns the time since which coordinator has been waiting to act on the current stage,
+// that is the latest of the times the stage was reached, the coordinator's turn came in a Sequential stage,
+// the coordinator was assigned to the stage, the coordinator was last escalated and the task was last resumed.
+// Since a reassignment records only the new coordinators of the stage, it restarts the wait of each of them.
+func (t *Task) ActiveSince(coordinator string) time.Time {
+	stage := t.Current()
+	sequential := stage != nil && (stage.Mode == "" || stage.Mode == Sequential)
+
+	since := t.submittedAt()
+	for _, e := range t.History {
+		if e.Revision != t.tag() || e.Stage > t.Stage {
+			continue
+		}
+
+		switch {
+		case e.Stage < t.Stage:
+			since = e.Time
+		case sequential && (e.Action == ActionApproved || e.Action == ActionSkipped):
+			since = e.Time
+		case e.Action == ActionResumed:
+			since = e.Time
+		case e.Action == ActionDelegated && e.Substitute == coordinator,
+			e.Action == ActionEscalated && e.Coordinator == coordinator,
+			e.Action == ActionReassigned && contains(e.Coordinators, coordinator):
+			since = e.Time
+		}
+	}
+
+	return since
+}
+
+// Stalled returns active coordinators who have been waiting longer than the SLA of the current stage at now.
+func (t *Task) Stalled(now time.Time) []string {
+	stage := t.Current()
+	if stage == nil || stage.SLA <= 0 {
+		return nil
+	}
+
+	var stalled []string
+	for _, c := range t.Active() {
+		if now.Sub(t.ActiveSince(c)) >= time.Duration(stage.SLA) {
+			stalled = append(stalled, c)
+		}
+	}
+
+	return stalled
+}
+
+// Breached reports whether any active coordinator has been waiting longer than the SLA of the current stage at now.
+func (t *Task) Breached(now time.Time) bool {
+	return len(t.Stalled(now)) > 0
+}
+
+// Escalate applies the escalation policy of the current stage to the stalled coordinators
+// and records it in the task history. Since escalation restarts the wait of the coordinators it concerns,
+// a policy which does not unblock the task is applied again after another SLA period.
+//
+// Delegation hands over a single coordinator, since the escalation contact can only take
+// one place in the stage. If the contact is already a coordinator of the stage, it is notified instead.
+// Escalate returns the policy which was applied.
+func (t *Task) Escalate(now time.Time) (EscalationPolicy, error) {
+	if !t.Breached(now) {
+		return "", ErrNotBreached
+	}
+
+	stage := t.Current()
+	stalled := t.Stalled(now)
+
+	policy := stage.Escalation
+	if policy == "" || policy == EscalateDelegate && stage.has(stage.EscalateTo) {
+		policy = EscalateNotify
+	}
+
+	switch policy {
+	case EscalateDelegate:
+		stage.replace(stalled[0], stage.EscalateTo)
+		t.addCoordinator(stage.EscalateTo)
+
+		return policy, t.Record(Event{Actor: SystemActor, Action: ActionDelegated, Stage: t.Stage, Time: now,
+			Coordinator: stalled[0], Substitute: stage.EscalateTo})
+
+	case EscalateSkip:
+		if err := t.Transition(InProgress); err != nil {
+			return policy, err
+		}
+		for _, c := range stalled {
+			stage.remove(c)
+			if err := t.Record(Event{Actor: SystemActor, Action: ActionSkipped, Stage: t.Stage, Time: now,
+				Coordinator: c}); err != nil {
+				return policy, err
+			}
+		}
+
+		if stage.Failed() || stage.Done() && stage.Approvals() == 0 {
+			return policy, t.Transition(Declined)
+		}
+
+		return policy, t.advance()
+
+	default:
+		for _, c := range stalled {
+			if err := t.Record(Event{Actor: SystemActor, Action: ActionEscalated, Stage: t.Stage, Time: now,
+				Coordinator: c, Substitute: stage.EscalateTo}); err != nil {
+				return policy, err
+			}
+		}
+
+		return policy, nil
+	}
+}
+
+// addCoordinator adds coordinator to the coordinators of the task if it is not one yet.
+func (t *Task) addCoordinator(coordinator string) {
+	if !contains(t.Coordinators, coordinator) {
+		t.Coordinators = append(t.Coordinators, coordinator)
+	}
+}
+
+func (s *Stage) has(coordinator string) bool {
+	return contains(s.Coordinators, coordinator)
+}
+
+func contains(emails []string, email string) bool {
+	for _, e := range emails {
+		if e == email {
+			return true
+		}
+	}
+
+	return false
+}
+
+// replace puts substitute in place of coordinator, keeping its position in the stage.
+func (s *Stage) replace(coordinator, substitute string) {
+	for i, c := range s.Coordinators {
+		if c == coordinator {
+			s.Coordinators[i] = substitute
+		}
+	}
+}
+
+// remove takes an undecided coordinator out of the stage. Quorum is lowered
+// if there are no longer enough coordinators to reach it.
+func (s *Stage) remove(coordinator string) {
+	coordinators := make([]string, 0, len(s.Coordinators))
+	for i, c := range s.Coordinators {
+		if c != coordinator {
+			coordinators = append(coordinators, c)
+		} else if i < s.Next {
+			s.Next--
+		}
+	}
+	s.Coordinators = coordinators
+
+	if s.Mode == Quorum && s.Quorum > len(s.Coordinators) {
+		s.Quorum = len(s.Coordinators)
+	}
+}
@@ -0,0 +1,235 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEscalate(t *testing.T) {
+	created := time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC)
+	newTask := func(policy EscalationPolicy, mode Mode) Task {
+		return Task{
+			Status:       NotStarted,
+			CreatedAt:    created,
+			Coordinators: []string{"a", "b"},
+			Stages: []Stage{{
+				Coordinators: []string{"a", "b"},
+				Mode:         mode,
+				SLA:          Duration(time.Hour),
+				Escalation:   policy,
+				EscalateTo:   "boss",
+			}},
+		}
+	}
+
+	t.Run("within SLA", func(t *testing.T) {
+		task := newTask(EscalateNotify, Sequential)
+		if _, err := task.Escalate(created.Add(59 * time.Minute)); err != ErrNotBreached {
+			t.Errorf("err = %v, want %v", err, ErrNotBreached)
+		}
+	})
+
+	t.Run("notify restarts the wait", func(t *testing.T) {
+		task := newTask(EscalateNotify, Parallel)
+		now := created.Add(2 * time.Hour)
+
+		policy, err := task.Escalate(now)
+		if err != nil || policy != EscalateNotify {
+			t.Fatalf("Escalate() = %v, %v, want %v", policy, err, EscalateNotify)
+		}
+		if n := len(task.History); n != 2 || task.History[0].Coordinator != "a" || task.History[1].Coordinator != "b" {
+			t.Fatalf("history = %+v, want an escalation of each stalled coordinator", task.History)
+		}
+		if task.Breached(now.Add(30 * time.Minute)) {
+			t.Error("task breached again before another SLA period")
+		}
+		if !task.Breached(now.Add(time.Hour)) {
+			t.Error("task not breached after another SLA period")
+		}
+	})
+
+	t.Run("delegate hands over the turn", func(t *testing.T) {
+		task := newTask(EscalateDelegate, Sequential)
+		now := created.Add(2 * time.Hour)
+
+		if _, err := task.Escalate(now); err != nil {
+			t.Fatal(err)
+		}
+		if got := task.Active(); !reflect.DeepEqual(got, []string{"boss"}) {
+			t.Errorf("Active() = %v, want [boss]", got)
+		}
+		if !task.Participant("boss") {
+			t.Error("escalation contact is not a coordinator of the task")
+		}
+		if got := task.ActiveSince("boss"); !got.Equal(now) {
+			t.Errorf("ActiveSince(boss) = %v, want %v", got, now)
+		}
+	})
+
+	t.Run("skip moves to the next coordinator", func(t *testing.T) {
+		task := newTask(EscalateSkip, Sequential)
+		now := created.Add(2 * time.Hour)
+
+		if _, err := task.Escalate(now); err != nil {
+			t.Fatal(err)
+		}
+		if got := task.Active(); !reflect.DeepEqual(got, []string{"b"}) {
+			t.Errorf("Active() = %v, want [b]", got)
+		}
+		if got := task.ActiveSince("b"); !got.Equal(now) {
+			t.Errorf("ActiveSince(b) = %v, want %v", got, now)
+		}
+		if task.Breached(now.Add(30 * time.Minute)) {
+			t.Error("next coordinator breached SLA before its own SLA period")
+		}
+	})
+
+}
+
+func TestEscalateSkip(t *testing.T) {
+	created := time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		stage     Stage
+		approve   []string
+		decline   []string
+		want      Status
+		remaining []string
+	}{
+		{
+			name:      "approved by the rest of a parallel stage",
+			stage:     Stage{Coordinators: []string{"a", "b"}, Mode: Parallel},
+			approve:   []string{"a"},
+			want:      Approved,
+			remaining: []string{"a"},
+		},
+		{
+			name:      "approved by the rest of a sequential stage",
+			stage:     Stage{Coordinators: []string{"a", "b"}, Mode: Sequential},
+			approve:   []string{"a"},
+			want:      Approved,
+			remaining: []string{"a"},
+		},
+		{
+			name:      "quorum lowered to the approvals given",
+			stage:     Stage{Coordinators: []string{"a", "b", "c"}, Mode: Quorum, Quorum: 2},
+			approve:   []string{"a"},
+			want:      Approved,
+			remaining: []string{"a"},
+		},
+		{
+			name:      "quorum unreachable after a refusal",
+			stage:     Stage{Coordinators: []string{"a", "b", "c"}, Mode: Quorum, Quorum: 2},
+			decline:   []string{"a"},
+			want:      Declined,
+			remaining: []string{"a"},
+		},
+		{
+			name:      "every coordinator skipped",
+			stage:     Stage{Coordinators: []string{"a", "b"}, Mode: Parallel},
+			want:      Declined,
+			remaining: []string{},
+		},
+		{
+			name:      "still waiting for a coordinator within the SLA",
+			stage:     Stage{Coordinators: []string{"a", "b"}, Mode: Parallel},
+			want:      InProgress,
+			remaining: []string{"b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stage := tt.stage
+			stage.SLA = Duration(time.Hour)
+			stage.Escalation = EscalateSkip
+			task := Task{Status: NotStarted, CreatedAt: created, Coordinators: stage.Coordinators, Stages: []Stage{stage}}
+
+			for _, c := range tt.approve {
+				if err := task.Approve(c, ""); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, c := range tt.decline {
+				if err := task.Decline(c, ""); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.want == InProgress {
+				// b was assigned again, so only a is past the SLA.
+				if err := task.Record(Event{Action: ActionReassigned, Time: created.Add(90 * time.Minute),
+					Coordinators: []string{"b"}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err := task.Escalate(created.Add(2 * time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+			if task.Status != tt.want {
+				t.Errorf("status = %v, want %v", task.Status, tt.want)
+			}
+			if got := task.Stages[0].Coordinators; !reflect.DeepEqual(got, tt.remaining) {
+				t.Errorf("coordinators = %v, want %v", got, tt.remaining)
+			}
+		})
+	}
+}
+
+func TestActiveSinceSequentialTurn(t *testing.T) {
+	created := time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC)
+	task := Task{
+		Status:    NotStarted,
+		CreatedAt: created,
+		Stages:    []Stage{{Coordinators: []string{"a", "b"}, Mode: Sequential, SLA: Duration(time.Hour)}},
+	}
+
+	approved := created.Add(50 * time.Minute)
+	if err := task.Approve("a", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := task.Record(Event{Actor: "a", Action: ActionApproved, Time: approved}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := task.ActiveSince("b"); !got.Equal(approved) {
+		t.Errorf("ActiveSince(b) = %v, want %v", got, approved)
+	}
+	if task.Breached(created.Add(90 * time.Minute)) {
+		t.Error("coordinator breached SLA counted from the creation of the task")
+	}
+}
+
+func TestActiveSinceResumed(t *testing.T) {
+	created := time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC)
+	task := Task{
+		Status:    InProgress,
+		CreatedAt: created,
+		Stages:    []Stage{{Coordinators: []string{"a", "b"}, Mode: Parallel, SLA: Duration(time.Hour)}},
+	}
+
+	// Time the task spends returned to its initiator does not count against the coordinators.
+	if err := task.Return("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := task.Record(Event{Actor: "a", Action: ActionReturned, Time: created.Add(10 * time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if task.Breached(created.Add(2 * time.Hour)) {
+		t.Error("returned task breached SLA")
+	}
+
+	resumed := created.Add(2 * time.Hour)
+	if err := task.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if err := task.Record(Event{Actor: "initiator", Action: ActionResumed, Time: resumed}); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []string{"a", "b"} {
+		if got := task.ActiveSince(c); !got.Equal(resumed) {
+			t.Errorf("ActiveSince(%v) = %v, want %v", c, got, resumed)
+		}
+	}
+}
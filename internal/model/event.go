@@ -0,0 +1,144 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Action is a kind of change recorded in task history.
+type Action string
+
+const (
+	ActionCreated  Action = "created"
+	ActionApproved Action = "approved"
+	ActionDeclined Action = "declined"
+	ActionExpired  Action = "expired"
+	// ActionWithdrawn is recorded when the initiator withdraws the task.
+	ActionWithdrawn Action = "withdrawn"
+	// ActionResubmitted is recorded when the initiator resubmits a declined task as a new revision.
+	ActionResubmitted Action = "resubmitted"
+	// ActionEscalated is recorded when the escalation contact is notified about Coordinator.
+	ActionEscalated Action = "escalated"
+	// ActionDelegated is recorded when Substitute takes the place of Coordinator.
+	ActionDelegated Action = "delegated"
+	// ActionSkipped is recorded when Coordinator is removed from the stage without a decision.
+	ActionSkipped Action = "skipped"
+	// ActionReassigned is recorded when the initiator changes Coordinators of the stage.
+	ActionReassigned Action = "reassigned"
+	// ActionReturned is recorded when a coordinator returns the task to the initiator for changes.
+	ActionReturned Action = "returned"
+	// ActionResumed is recorded when the initiator resumes a returned task.
+	ActionResumed Action = "resumed"
+)
+
+// SystemActor is the actor of events caused by the service itself rather than by a user.
+const SystemActor = "system"
+
+// Event is an entry of the task audit trail. Events are hash-chained: Hash covers
+// the canonical JSON form of the event including PrevHash, which is the Hash of the previous event.
+// New fields must be tagged omitempty to keep hashes of existing events valid.
+type Event struct {
+	Actor     string    `json:"actor" bson:"actor"`
+	Action    Action    `json:"action" bson:"action"`
+	Stage     int       `json:"stage" bson:"stage"`
+	Time      time.Time `json:"time" bson:"time"`
+	RequestID string    `json:"request_id,omitempty" bson:"request_id,omitempty"`
+	IP        string    `json:"ip,omitempty" bson:"ip,omitempty"`
+	// Coordinator and Substitute are the coordinators which the change concerns, if it is not the actor.
+	Coordinator string `json:"coordinator,omitempty" bson:"coordinator,omitempty"`
+	Substitute  string `json:"substitute,omitempty" bson:"substitute,omitempty"`
+	// Coordinators is the new list of coordinators of the stage after a reassignment.
+	Coordinators []string `json:"coordinators,omitempty" bson:"coordinators,omitempty"`
+	// Revision is the revision the event belongs to. It is omitted for the first revision.
+	Revision int `json:"revision,omitempty" bson:"revision,omitempty"`
+	// Comment is the comment or reason the coordinator has given for their decision.
+	Comment  string `json:"comment,omitempty" bson:"comment,omitempty"`
+	PrevHash string `json:"prev_hash,omitempty" bson:"prev_hash,omitempty"`
+	Hash     string `json:"hash" bson:"hash"`
+}
+
+// Digest computes SHA-256 over the canonical form of the event, which excludes Hash itself.
+func (e Event) Digest() (string, error) {
+	e.Hash = ""
+	e.Time = e.Time.UTC()
+
+	canonical, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canonical)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Record chains e to the last event of the task history and appends it. Events recorded since
+// the task was loaded are appended to the stored history on the next update and are never rewritten.
+// Events of revisions after the first one are tagged with the revision number.
+func (t *Task) Record(e Event) error {
+	e.Revision = t.tag()
+	if len(t.History) > 0 {
+		e.PrevHash = t.History[len(t.History)-1].Hash
+	}
+
+	hash, err := e.Digest()
+	if err != nil {
+		return err
+	}
+	e.Hash = hash
+
+	t.History = append(t.History, e)
+	t.recorded = append(t.recorded, e)
+
+	return nil
+}
+
+// Recorded returns events recorded since the task was loaded or last saved.
+func (t *Task) Recorded() []Event {
+	return t.recorded
+}
+
+// Saved marks all recorded events as stored.
+func (t *Task) Saved() {
+	t.recorded = nil
+}
+
+// VerifyHistory walks the hash chain of the task history and returns the index
+// of the first event which is not linked to its predecessor or whose hash does not match.
+// It returns -1 if the whole chain is intact.
+func (t *Task) VerifyHistory() (int, error) {
+	var prev string
+	for i, e := range t.History {
+		if e.PrevHash != prev {
+			return i, nil
+		}
+
+		hash, err := e.Digest()
+		if err != nil {
+			return i, err
+		}
+		if hash != e.Hash {
+			return i, nil
+		}
+
+		prev = e.Hash
+	}
+
+	return -1, nil
+}
+
+// Participant reports whether email is the initiator or one of the coordinators of the task.
+func (t *Task) Participant(email string) bool {
+	if t.Initiator == email {
+		return true
+	}
+	for _, c := range t.Coordinators {
+		if c == email {
+			return true
+		}
+	}
+
+	return false
+}
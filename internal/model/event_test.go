@@ -0,0 +1,112 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerifyHistory(t *testing.T) {
+	now := time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC)
+	newTask := func(t *testing.T) Task {
+		var task Task
+		for i, action := range []Action{ActionCreated, ActionApproved, ActionDeclined} {
+			if err := task.Record(Event{Actor: "a", Action: action, Time: now.Add(time.Duration(i) * time.Minute)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return task
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(task *Task)
+		want   int
+	}{
+		{
+			name:   "intact",
+			tamper: func(task *Task) {},
+			want:   -1,
+		},
+		{
+			name:   "changed event",
+			tamper: func(task *Task) { task.History[1].Actor = "b" },
+			want:   1,
+		},
+		{
+			name:   "removed event",
+			tamper: func(task *Task) { task.History = append(task.History[:1], task.History[2:]...) },
+			want:   1,
+		},
+		{
+			name: "rehashed event",
+			tamper: func(task *Task) {
+				task.History[0].Actor = "b"
+				task.History[0].Hash, _ = task.History[0].Digest()
+			},
+			want: 1,
+		},
+		{
+			name:   "time zone",
+			tamper: func(task *Task) { task.History[2].Time = task.History[2].Time.In(time.FixedZone("UTC+3", 3*3600)) },
+			want:   -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := newTask(t)
+			tt.tamper(&task)
+
+			got, err := task.VerifyHistory()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("VerifyHistory() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordChainsEvents(t *testing.T) {
+	var task Task
+	for _, action := range []Action{ActionCreated, ActionApproved} {
+		if err := task.Record(Event{Actor: "a", Action: action}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if task.History[0].PrevHash != "" || task.History[1].PrevHash != task.History[0].Hash {
+		t.Error("events are not chained")
+	}
+	if len(task.Recorded()) != 2 {
+		t.Errorf("recorded %v events, want 2", len(task.Recorded()))
+	}
+
+	task.Saved()
+	if len(task.Recorded()) != 0 {
+		t.Errorf("recorded %v events after saving, want none", len(task.Recorded()))
+	}
+}
+
+func TestRecordTagsRevision(t *testing.T) {
+	task := Task{Revision: 1}
+	if err := task.Record(Event{Action: ActionCreated}); err != nil {
+		t.Fatal(err)
+	}
+	task.Revision = 2
+	if err := task.Record(Event{Action: ActionResubmitted}); err != nil {
+		t.Fatal(err)
+	}
+
+	if task.History[0].Revision != 0 || task.History[1].Revision != 2 {
+		t.Errorf("revisions = %v, %v, want 0, 2", task.History[0].Revision, task.History[1].Revision)
+	}
+	if task.History[1].PrevHash != task.History[0].Hash {
+		t.Error("event is not chained to its predecessor")
+	}
+	if len(task.Recorded()) != 2 {
+		t.Errorf("recorded %v events, want 2", len(task.Recorded()))
+	}
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrStageDecided = errors.New("stage has already been decided")
+
+var ErrDecidedCoordinator = errors.New("coordinators who have decided must keep their place in the stage")
+
+var ErrNothingPending = errors.New("stage must keep at least one coordinator awaiting decision")
+
+// Reassign replaces coordinators of the given stage, which swaps, adds, removes or reorders
+// coordinators who have not decided yet. Coordinators who have decided must stay in the stage
+// and in Sequential mode also keep their positions. If quorum is positive, it replaces the quorum
+// of the stage. The stage must still await a decision afterwards, so that the task can't
+// complete or fail without one.
+func (t *Task) Reassign(stage int, coordinators []string, quorum int) error {
+	if !t.Status.CanTransition(InProgress) {
+		return transitionError(t.Status, InProgress)
+	}
+	if stage < t.Stage || stage >= len(t.Stages) {
+		return ErrStageDecided
+	}
+
+	current := &t.Stages[stage]
+	updated := *current
+	updated.Coordinators = coordinators
+	if quorum > 0 {
+		updated.Quorum = quorum
+	}
+
+	seen := make(map[string]bool, len(coordinators))
+	for _, c := range coordinators {
+		if seen[c] {
+			return ErrDuplicateCoordinator
+		}
+		seen[c] = true
+	}
+	for _, d := range current.Decisions {
+		if !seen[d.Coordinator] {
+			return ErrDecidedCoordinator
+		}
+	}
+	if updated.Mode == "" || updated.Mode == Sequential {
+		for i := 0; i < current.Next; i++ {
+			if i >= len(coordinators) || coordinators[i] != current.Coordinators[i] {
+				return ErrDecidedCoordinator
+			}
+		}
+	}
+
+	if len(updated.Active()) == 0 {
+		return ErrNothingPending
+	}
+
+	*current = updated
+	for _, c := range coordinators {
+		t.addCoordinator(c)
+	}
+
+	return nil
+}
+
+// Unassigned reports whether coordinator has been taken off the given stage of the current revision
+// after since by a reassignment, a delegation or a skip, even if they have been assigned to it again.
+// Events are stored with millisecond precision, so a change made in the same millisecond as since
+// is taken to precede it, like the reassignment which makes a coordinator active before they are mailed.
+func (t *Task) Unassigned(stage int, coordinator string, since time.Time) bool {
+	for _, e := range t.History {
+		if e.Revision != t.tag() || e.Stage != stage || !e.Time.After(since) {
+			continue
+		}
+
+		switch {
+		case e.Action == ActionReassigned && !contains(e.Coordinators, coordinator),
+			(e.Action == ActionDelegated || e.Action == ActionSkipped) && e.Coordinator == coordinator:
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,182 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrNotDeclined = errors.New("only declined tasks can be resubmitted")
+
+var ErrUnknownRevision = errors.New("task has no such revision")
+
+// Revision is a submitted version of a task along with the decisions made on it.
+// Previous revisions of a task are kept unchanged in Task.Revisions.
+type Revision struct {
+	Number      int        `json:"number" bson:"number"`
+	Name        string     `json:"name" bson:"name"`
+	Description string     `json:"description" bson:"description"`
+	Stages      []Stage    `json:"stages" bson:"stages"`
+	Status      Status     `json:"status" bson:"status"`
+	DueAt       *time.Time `json:"due_at,omitempty" bson:"due_at,omitempty"`
+	SubmittedAt time.Time  `json:"submitted_at" bson:"submitted_at"`
+}
+
+// Change is a difference between two revisions. Field is the JSON path of the changed value.
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+// Number returns the number of the current revision. Tasks stored before revisions
+// were introduced have no revision number and are at revision 1.
+func (t *Task) Number() int {
+	if t.Revision == 0 {
+		return 1
+	}
+
+	return t.Revision
+}
+
+// tag returns the revision number events of the current revision are tagged with,
+// which is 0 for the first revision.
+func (t *Task) tag() int {
+	if t.Number() > 1 {
+		return t.Number()
+	}
+
+	return 0
+}
+
+// Snapshot returns the current revision of the task.
+func (t *Task) Snapshot() Revision {
+	return Revision{
+		Number:      t.Number(),
+		Name:        t.Name,
+		Description: t.Description,
+		Stages:      t.Stages,
+		Status:      t.Status,
+		DueAt:       t.DueAt,
+		SubmittedAt: t.submittedAt(),
+	}
+}
+
+// AllRevisions returns previous revisions of the task followed by the current one.
+func (t *Task) AllRevisions() []Revision {
+	return append(append([]Revision(nil), t.Revisions...), t.Snapshot())
+}
+
+// FindRevision returns the revision with the given number.
+func (t *Task) FindRevision(number int) (Revision, error) {
+	for _, r := range t.AllRevisions() {
+		if r.Number == number {
+			return r, nil
+		}
+	}
+
+	return Revision{}, ErrUnknownRevision
+}
+
+// Resubmit keeps the current revision of a declined task and starts the next one with the given
+// content and stages. A new revision starts the lifecycle over, so the task moves back to NotStarted.
+func (t *Task) Resubmit(name, description string, stages []Stage, dueAt *time.Time) error {
+	if !t.Status.CanTransition(NotStarted) {
+		return ErrNotDeclined
+	}
+
+	t.Revisions = append(t.Revisions, t.Snapshot())
+	t.Revision = t.Number() + 1
+	t.Name = name
+	t.Description = description
+	t.Stages = stages
+	t.Stage = 0
+	t.DueAt = dueAt
+
+	for _, s := range stages {
+		for _, c := range s.Coordinators {
+			t.addCoordinator(c)
+		}
+	}
+
+	return t.Transition(NotStarted)
+}
+
+// submittedAt returns the time the current revision was submitted at.
+func (t *Task) submittedAt() time.Time {
+	for i := len(t.History) - 1; i >= 0; i-- {
+		if t.History[i].Action == ActionResubmitted {
+			return t.History[i].Time
+		}
+	}
+
+	return t.CreatedAt
+}
+
+// Diff returns changes of content and stage configuration made between revisions from and to.
+// Decisions are not compared, since every revision is decided on anew.
+func Diff(from, to Revision) []Change {
+	var changes []Change
+	compare := func(field, before, after string) {
+		if before != after {
+			changes = append(changes, Change{Field: field, Old: before, New: after})
+		}
+	}
+
+	compare("name", from.Name, to.Name)
+	compare("description", from.Description, to.Description)
+	compare("due_at", formatTime(from.DueAt), formatTime(to.DueAt))
+
+	stages := len(from.Stages)
+	if len(to.Stages) > stages {
+		stages = len(to.Stages)
+	}
+	for i := 0; i < stages; i++ {
+		before, after := stageFields(from.Stages, i), stageFields(to.Stages, i)
+		for j := range before {
+			compare(fmt.Sprintf("stages[%d].%v", i, before[j][0]), before[j][1], after[j][1])
+		}
+	}
+
+	return changes
+}
+
+// stageFields returns names and values of compared fields of the i-th stage.
+// A missing stage has all values empty.
+func stageFields(stages []Stage, i int) [][2]string {
+	var s Stage
+	var mode, quorum, sla string
+	if i < len(stages) {
+		s = stages[i]
+		mode = string(Sequential)
+		if s.Mode != "" {
+			mode = string(s.Mode)
+		}
+		if s.Mode == Quorum {
+			quorum = strconv.Itoa(s.Quorum)
+		}
+		if s.SLA > 0 {
+			sla = time.Duration(s.SLA).String()
+		}
+	}
+
+	return [][2]string{
+		{"name", s.Name},
+		{"coordinators", strings.Join(s.Coordinators, ", ")},
+		{"mode", mode},
+		{"quorum", quorum},
+		{"sla", sla},
+		{"escalation", string(s.Escalation)},
+		{"escalate_to", s.EscalateTo},
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
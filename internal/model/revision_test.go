@@ -0,0 +1,81 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	due := time.Date(2023, 1, 10, 12, 0, 0, 0, time.UTC)
+	from := Revision{
+		Name:        "Contract",
+		Description: "Draft",
+		Stages: []Stage{
+			{Coordinators: []string{"a", "b"}},
+		},
+	}
+	to := Revision{
+		Name:        "Contract",
+		Description: "Final",
+		DueAt:       &due,
+		Stages: []Stage{
+			{Coordinators: []string{"a", "b"}, Mode: Sequential, Decisions: []Decision{{Coordinator: "a", Approved: true}}},
+			{Name: "Legal", Coordinators: []string{"c", "d"}, Mode: Quorum, Quorum: 1, SLA: Duration(48 * time.Hour)},
+		},
+	}
+
+	want := []Change{
+		{Field: "description", Old: "Draft", New: "Final"},
+		{Field: "due_at", New: "2023-01-10T12:00:00Z"},
+		{Field: "stages[1].name", New: "Legal"},
+		{Field: "stages[1].coordinators", New: "c, d"},
+		{Field: "stages[1].mode", New: "quorum"},
+		{Field: "stages[1].quorum", New: "1"},
+		{Field: "stages[1].sla", New: "48h0m0s"},
+	}
+	if got := Diff(from, to); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %+v, want %+v", got, want)
+	}
+
+	if got := Diff(to, to); got != nil {
+		t.Errorf("Diff() of the same revision = %+v, want none", got)
+	}
+}
+
+func TestResubmit(t *testing.T) {
+	task := Task{
+		Status:       NotStarted,
+		Coordinators: []string{"a"},
+		Stages:       []Stage{{Coordinators: []string{"a"}, Mode: Sequential}},
+	}
+
+	if err := task.Resubmit("n", "d", nil, nil); err != ErrNotDeclined {
+		t.Fatalf("Resubmit of a pending task: err = %v, want %v", err, ErrNotDeclined)
+	}
+
+	if err := task.Decline("a", "no"); err != nil {
+		t.Fatal(err)
+	}
+	if err := task.Resubmit("n", "d", []Stage{{Coordinators: []string{"a", "b"}, Mode: Sequential}}, nil); err != nil {
+		t.Fatalf("Resubmit: %v", err)
+	}
+
+	if task.Status != NotStarted || task.Number() != 2 {
+		t.Errorf("status %v, revision %v, want %v, 2", task.Status, task.Number(), NotStarted)
+	}
+	if !reflect.DeepEqual(task.Coordinators, []string{"a", "b"}) {
+		t.Errorf("coordinators = %v, want [a b]", task.Coordinators)
+	}
+
+	previous, err := task.FindRevision(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous.Status != Declined || len(previous.Stages[0].Decisions) != 1 {
+		t.Errorf("previous revision = %+v, want the declined one", previous)
+	}
+	if _, err = task.FindRevision(3); err != ErrUnknownRevision {
+		t.Errorf("FindRevision(3): err = %v, want %v", err, ErrUnknownRevision)
+	}
+}
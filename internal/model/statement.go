@@ -0,0 +1,108 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Statement is the canonical content of a coordinator decision signed by the service.
+// DecidedBy is omitted for decisions made by the coordinator, Revision for decisions
+// on the first revision and Comment for decisions without one, so that signatures made
+// before they were introduced stay valid.
+type Statement struct {
+	TaskID      string    `json:"task_id"`
+	Stage       int       `json:"stage"`
+	Coordinator string    `json:"coordinator"`
+	Decision    Action    `json:"decision"`
+	Time        time.Time `json:"time"`
+	ContentHash string    `json:"content_hash"`
+	DecidedBy   string    `json:"decided_by,omitempty"`
+	Revision    int       `json:"revision,omitempty"`
+	Comment     string    `json:"comment,omitempty"`
+}
+
+// Canonical returns the exact bytes which are signed.
+func (s Statement) Canonical() ([]byte, error) {
+	s.Time = s.Time.UTC()
+
+	return json.Marshal(s)
+}
+
+// ContentHash returns hex encoded SHA-256 of the task name and description.
+func (t *Task) ContentHash() (string, error) {
+	return contentHash(t.Name, t.Description)
+}
+
+// ContentHash returns hex encoded SHA-256 of the revision name and description.
+func (r Revision) ContentHash() (string, error) {
+	return contentHash(r.Name, r.Description)
+}
+
+func contentHash(name, description string) (string, error) {
+	content, err := json.Marshal(struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}{name, description})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Statement returns the statement of coordinator's decision in the given stage of the current revision.
+func (t *Task) Statement(stage int, coordinator string) (Statement, *Decision, bool) {
+	return t.statement(t.Number(), t.Stages, stage, coordinator)
+}
+
+// RevisionStatement returns the statement of coordinator's decision in the given stage of revision number.
+func (t *Task) RevisionStatement(number, stage int, coordinator string) (Statement, *Decision, bool) {
+	if number == t.Number() {
+		return t.Statement(stage, coordinator)
+	}
+
+	for i := range t.Revisions {
+		if t.Revisions[i].Number == number {
+			return t.statement(number, t.Revisions[i].Stages, stage, coordinator)
+		}
+	}
+
+	return Statement{}, nil, false
+}
+
+func (t *Task) statement(number int, stages []Stage, stage int, coordinator string) (Statement, *Decision, bool) {
+	if stage < 0 || stage >= len(stages) {
+		return Statement{}, nil, false
+	}
+
+	d := stages[stage].Decision(coordinator)
+	if d == nil {
+		return Statement{}, nil, false
+	}
+
+	decision := ActionDeclined
+	if d.Approved {
+		decision = ActionApproved
+	}
+
+	var revision int
+	if number > 1 {
+		revision = number
+	}
+
+	return Statement{
+		TaskID:      t.ID.Hex(),
+		Stage:       stage,
+		Coordinator: d.Coordinator,
+		Decision:    decision,
+		Time:        d.Time,
+		ContentHash: d.ContentHash,
+		DecidedBy:   d.DecidedBy,
+		Revision:    revision,
+		Comment:     d.Comment,
+	}, d, true
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestContentHash(t *testing.T) {
+	hash := func(task Task) string {
+		h, err := task.ContentHash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	task := Task{Name: "Contract", Description: "Draft", Status: InProgress}
+	original := hash(task)
+
+	task.Status = Approved
+	if hash(task) != original {
+		t.Error("content hash depends on the status")
+	}
+	task.Description = "Final"
+	if hash(task) == original {
+		t.Error("content hash does not depend on the description")
+	}
+	// Name and description are hashed as separate fields.
+	if hash(Task{Name: "ab", Description: "c"}) == hash(Task{Name: "a", Description: "bc"}) {
+		t.Error("content hash is ambiguous")
+	}
+}
+
+func TestStatement(t *testing.T) {
+	at := time.Date(2022, 12, 1, 10, 0, 0, 0, time.FixedZone("UTC+3", 3*3600))
+	task := Task{Stages: []Stage{{
+		Coordinators: []string{"a", "b"},
+		Decisions: []Decision{
+			{Coordinator: "a", Approved: true, Time: at, ContentHash: "h"},
+			{Coordinator: "b", Time: at},
+		},
+	}}}
+
+	statement, decision, ok := task.Statement(0, "a")
+	if !ok || decision != &task.Stages[0].Decisions[0] {
+		t.Fatalf("Statement(0, a) = %+v, %v", statement, ok)
+	}
+	if statement.Decision != ActionApproved || statement.ContentHash != "h" {
+		t.Errorf("statement = %+v", statement)
+	}
+	if declined, _, _ := task.Statement(0, "b"); declined.Decision != ActionDeclined {
+		t.Errorf("decision of b = %v, want %v", declined.Decision, ActionDeclined)
+	}
+
+	for _, missing := range []struct {
+		stage       int
+		coordinator string
+	}{{0, "c"}, {1, "a"}, {-1, "a"}} {
+		if _, _, ok = task.Statement(missing.stage, missing.coordinator); ok {
+			t.Errorf("Statement(%v, %v) found", missing.stage, missing.coordinator)
+		}
+	}
+
+	// The signed form does not depend on the time zone the decision time was read in.
+	utc, _, _ := task.Statement(0, "a")
+	utc.Time = utc.Time.UTC()
+	a, err := statement.Canonical()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := utc.Canonical()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a) != string(b) {
+		t.Errorf("canonical forms differ:\n%s\n%s", a, b)
+	}
+}
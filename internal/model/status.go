@@ -0,0 +1,129 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// Status is a state of the task lifecycle. Changes between statuses are only allowed
+// along the transitions table and must go through Task.Transition.
+type Status string
+
+const (
+	NotStarted Status = "not_started"
+	InProgress Status = "in_progress"
+	Approved   Status = "approved"
+	Declined   Status = "declined"
+	Withdrawn  Status = "withdrawn"
+	Expired    Status = "expired"
+	Returned   Status = "returned"
+)
+
+// transitions lists statuses reachable from each status. InProgress leads to itself,
+// so that a decision which does not complete a task is still validated. Declined leads
+// to NotStarted only when the task is resubmitted, which starts the lifecycle of a new revision.
+var transitions = map[Status][]Status{
+	NotStarted: {InProgress, Withdrawn, Expired},
+	InProgress: {InProgress, Approved, Declined, Withdrawn, Expired, Returned},
+	Returned:   {InProgress, Withdrawn},
+	Approved:   {},
+	Declined:   {NotStarted},
+	Withdrawn:  {},
+	Expired:    {},
+}
+
+// legacyStatuses maps numeric statuses stored before Status became a string.
+var legacyStatuses = map[int64]Status{
+	1: NotStarted,
+	2: InProgress,
+	3: Approved,
+	4: Declined,
+}
+
+var ErrInvalidStatus = errors.New("unknown task status")
+
+var ErrInvalidTransition = errors.New("status transition is not allowed")
+
+// Valid reports whether s is a known status.
+func (s Status) Valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// Final reports whether no further transitions are possible from s within the current revision.
+// A declined task is final, even though it can be resubmitted as a new revision.
+func (s Status) Final() bool {
+	for _, to := range transitions[s] {
+		if to != NotStarted {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CanTransition reports whether the transitions table allows moving from s to next.
+func (s Status) CanTransition(next Status) bool {
+	for _, to := range transitions[s] {
+		if to == next {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UnmarshalBSONValue decodes status stored either as string or as a legacy number.
+func (s *Status) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	var status Status
+
+	switch t {
+	case bsontype.String:
+		str, _, ok := bsoncore.ReadString(data)
+		if !ok {
+			return ErrInvalidStatus
+		}
+		status = Status(str)
+
+	case bsontype.Int32:
+		i, _, ok := bsoncore.ReadInt32(data)
+		if !ok {
+			return ErrInvalidStatus
+		}
+		status = legacyStatuses[int64(i)]
+
+	case bsontype.Int64:
+		i, _, ok := bsoncore.ReadInt64(data)
+		if !ok {
+			return ErrInvalidStatus
+		}
+		status = legacyStatuses[i]
+
+	default:
+		return fmt.Errorf("%w: cannot decode %v", ErrInvalidStatus, t)
+	}
+
+	if !status.Valid() {
+		return ErrInvalidStatus
+	}
+	*s = status
+
+	return nil
+}
+
+// Transition moves the task to next status if the transitions table allows it.
+func (t *Task) Transition(next Status) error {
+	if !t.Status.CanTransition(next) {
+		return transitionError(t.Status, next)
+	}
+	t.Status = next
+
+	return nil
+}
+
+func transitionError(from, to Status) error {
+	return fmt.Errorf("%w: %v -> %v", ErrInvalidTransition, from, to)
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		from, to Status
+		allowed  bool
+	}{
+		{from: NotStarted, to: InProgress, allowed: true},
+		{from: NotStarted, to: Withdrawn, allowed: true},
+		{from: NotStarted, to: Expired, allowed: true},
+		{from: NotStarted, to: Approved},
+		{from: InProgress, to: InProgress, allowed: true},
+		{from: InProgress, to: Approved, allowed: true},
+		{from: InProgress, to: Declined, allowed: true},
+		{from: InProgress, to: Returned, allowed: true},
+		{from: Returned, to: InProgress, allowed: true},
+		{from: Returned, to: Approved},
+		{from: Approved, to: InProgress},
+		{from: Declined, to: Approved},
+		{from: Withdrawn, to: InProgress},
+		{from: Expired, to: Withdrawn},
+	}
+
+	for _, tt := range tests {
+		task := Task{Status: tt.from}
+		err := task.Transition(tt.to)
+
+		if tt.allowed && (err != nil || task.Status != tt.to) {
+			t.Errorf("%v -> %v: status %v, err %v, want allowed", tt.from, tt.to, task.Status, err)
+		}
+		if !tt.allowed && (!errors.Is(err, ErrInvalidTransition) || task.Status != tt.from) {
+			t.Errorf("%v -> %v: status %v, err %v, want rejected", tt.from, tt.to, task.Status, err)
+		}
+	}
+}
+
+func TestStatusFinal(t *testing.T) {
+	for status, final := range map[Status]bool{
+		NotStarted: false,
+		InProgress: false,
+		Returned:   false,
+		Approved:   true,
+		Declined:   true,
+		Withdrawn:  true,
+		Expired:    true,
+	} {
+		if got := status.Final(); got != final {
+			t.Errorf("%v.Final() = %v, want %v", status, got, final)
+		}
+	}
+}
+
+func TestStatusUnmarshalBSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		stored  interface{}
+		want    Status
+		wantErr bool
+	}{
+		{name: "string", stored: "in_progress", want: InProgress},
+		{name: "legacy int32", stored: int32(3), want: Approved},
+		{name: "legacy int64", stored: int64(4), want: Declined},
+		{name: "legacy fatal error", stored: int32(0), wantErr: true},
+		{name: "unknown string", stored: "paused", wantErr: true},
+		{name: "wrong type", stored: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := bson.Marshal(bson.M{"status": tt.stored})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var decoded struct {
+				Status Status `bson:"status"`
+			}
+			err = bson.Unmarshal(raw, &decoded)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && decoded.Status != tt.want {
+				t.Errorf("status = %v, want %v", decoded.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusJSON(t *testing.T) {
+	encoded, err := json.Marshal(Task{Status: NotStarted})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err = json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["status"] != "not_started" {
+		t.Errorf("status = %v, want not_started", decoded["status"])
+	}
+}
@@ -1,25 +1,197 @@
 package model
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
 
-const (
-	FatalError = 0
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Mode defines how coordinators of a stage are expected to approve it.
+type Mode string
 
-	NotStarted = iota
-	InProgress
-	Approved
-	Declined
+const (
+	// Sequential mode requires every coordinator to approve one after another in the listed order.
+	Sequential Mode = "sequential"
+	// Parallel mode requires every coordinator to approve in any order.
+	Parallel Mode = "parallel"
+	// Quorum mode requires any Quorum of the coordinators to approve in any order.
+	Quorum Mode = "quorum"
 )
 
-type Status uint8
+// Valid reports whether m is a known approval mode. Empty mode is treated as Sequential.
+func (m Mode) Valid() bool {
+	switch m {
+	case "", Sequential, Parallel, Quorum:
+		return true
+	}
 
-// Task represents a coordination service task.
+	return false
+}
+
+// Decision represents a single coordinator's verdict on a stage.
+// DecidedBy is set if the decision was made by a delegate on behalf of Coordinator.
+// Signature is the service signature of the decision Statement.
+type Decision struct {
+	Coordinator string    `json:"coordinator" bson:"coordinator"`
+	Approved    bool      `json:"approved" bson:"approved"`
+	Time        time.Time `json:"time" bson:"time"`
+	DecidedBy   string    `json:"decided_by,omitempty" bson:"decided_by,omitempty"`
+	Comment     string    `json:"comment,omitempty" bson:"comment,omitempty"`
+	ContentHash string    `json:"content_hash,omitempty" bson:"content_hash,omitempty"`
+	Signature   string    `json:"signature,omitempty" bson:"signature,omitempty"`
+}
+
+// Stage is a single step of a task approval pipeline with its own coordinators and completion rule.
+// If SLA is set, coordinators who keep the stage waiting longer are escalated to EscalateTo.
+type Stage struct {
+	Name         string           `json:"name,omitempty" bson:"name,omitempty"`
+	Coordinators []string         `json:"coordinators" bson:"coordinators"`
+	Mode         Mode             `json:"mode" bson:"mode"`
+	Quorum       int              `json:"quorum,omitempty" bson:"quorum,omitempty"`
+	Decisions    []Decision       `json:"decisions" bson:"decisions"`
+	Next         int              `json:"next" bson:"next"`
+	SLA          Duration         `json:"sla,omitempty" bson:"sla,omitempty"`
+	Escalation   EscalationPolicy `json:"escalation,omitempty" bson:"escalation,omitempty"`
+	EscalateTo   string           `json:"escalate_to,omitempty" bson:"escalate_to,omitempty"`
+}
+
+// Task represents a coordination service task. Coordinators holds everyone who has been
+// a coordinator of any stage, including those who were later substituted or belong to previous revisions.
+// Revision is the number of the current revision, previous ones are kept in Revisions.
 type Task struct {
 	ID           primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
 	Name         string             `json:"name" bson:"name"`
 	Description  string             `json:"description" bson:"description"`
 	Initiator    string             `json:"initiator" bson:"initiator"`
 	Coordinators []string           `json:"coordinators" bson:"coordinators"`
-	Next         int                `json:"next" bson:"next"`
+	Stages       []Stage            `json:"stages" bson:"stages"`
+	Stage        int                `json:"stage" bson:"stage"`
 	Status       Status             `json:"status" bson:"status"`
+	Version      int64              `json:"version" bson:"version"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
+	DueAt        *time.Time         `json:"due_at,omitempty" bson:"due_at,omitempty"`
+	Revision     int                `json:"revision,omitempty" bson:"revision,omitempty"`
+	Revisions    []Revision         `json:"revisions,omitempty" bson:"revisions,omitempty"`
+	History      []Event            `json:"history" bson:"history"`
+	DataKey      string             `json:"-" bson:"data_key,omitempty"`
+
+	recorded []Event
+}
+
+// UnmarshalBSON decodes task. Tasks stored before approval stages keep their coordinators, approval mode
+// and decisions on the task itself, so they are converted into tasks with a single stage.
+func (t *Task) UnmarshalBSON(data []byte) error {
+	type task Task
+	var stored struct {
+		Task      task       `bson:",inline"`
+		Mode      Mode       `bson:"mode"`
+		Quorum    int        `bson:"quorum"`
+		Decisions []Decision `bson:"decisions"`
+		Next      int        `bson:"next"`
+	}
+	if err := bson.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+
+	*t = Task(stored.Task)
+	if len(t.Stages) > 0 || len(t.Coordinators) == 0 {
+		return nil
+	}
+
+	stage := Stage{
+		Coordinators: t.Coordinators,
+		Mode:         stored.Mode,
+		Quorum:       stored.Quorum,
+		Decisions:    stored.Decisions,
+		Next:         stored.Next,
+	}
+	if stage.Mode == "" {
+		stage.Mode = Sequential
+	}
+	if stage.Decisions == nil {
+		stage.Decisions = legacyDecisions(t.Coordinators, stored.Next, t.Status)
+	}
+	t.Stages = []Stage{stage}
+	t.Stage = 0
+
+	return nil
+}
+
+// legacyDecisions returns decisions of a task stored before approval modes, which only kept the position
+// of the current coordinator. Coordinators before it have approved and the current one has made the final
+// decision, if the task is finished.
+func legacyDecisions(coordinators []string, next int, status Status) []Decision {
+	approvals := next
+	if status == Approved {
+		approvals = next + 1
+	}
+
+	var decisions []Decision
+	for i := 0; i < approvals && i < len(coordinators); i++ {
+		decisions = append(decisions, Decision{Coordinator: coordinators[i], Approved: true})
+	}
+	if status == Declined && next < len(coordinators) {
+		decisions = append(decisions, Decision{Coordinator: coordinators[next]})
+	}
+
+	return decisions
+}
+
+// SensitiveFields returns pointers to fields which are encrypted at rest when it is enabled,
+// which are names, descriptions and decision comments of every revision.
+func (t *Task) SensitiveFields() []*string {
+	fields := []*string{&t.Name, &t.Description}
+	fields = appendComments(fields, t.Stages)
+	for i := range t.Revisions {
+		fields = append(fields, &t.Revisions[i].Name, &t.Revisions[i].Description)
+		fields = appendComments(fields, t.Revisions[i].Stages)
+	}
+	for i := range t.History {
+		fields = append(fields, &t.History[i].Comment)
+	}
+	for i := range t.recorded {
+		fields = append(fields, &t.recorded[i].Comment)
+	}
+
+	return fields
+}
+
+func appendComments(fields []*string, stages []Stage) []*string {
+	for i := range stages {
+		for j := range stages[i].Decisions {
+			fields = append(fields, &stages[i].Decisions[j].Comment)
+		}
+	}
+
+	return fields
+}
+
+// Detached returns a copy of the task which shares none of its sensitive fields,
+// so that they can be encrypted in place without changing the task.
+func (t *Task) Detached() Task {
+	detached := *t
+	detached.Stages = cloneStages(t.Stages)
+	detached.Revisions = append([]Revision(nil), t.Revisions...)
+	for i := range detached.Revisions {
+		detached.Revisions[i].Stages = cloneStages(t.Revisions[i].Stages)
+	}
+	detached.History = append([]Event(nil), t.History...)
+	detached.recorded = append([]Event(nil), t.recorded...)
+
+	return detached
+}
+
+func cloneStages(stages []Stage) []Stage {
+	if stages == nil {
+		return nil
+	}
+
+	cloned := append([]Stage(nil), stages...)
+	for i := range cloned {
+		cloned[i].Decisions = append([]Decision(nil), stages[i].Decisions...)
+	}
+
+	return cloned
 }
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestUnmarshalLegacyTask(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored bson.M
+		want   []Stage
+		active []string
+	}{
+		{
+			name:   "before approval modes, pending",
+			stored: bson.M{"coordinators": bson.A{"a", "b", "c"}, "next": 1, "status": InProgress},
+			want: []Stage{{Coordinators: []string{"a", "b", "c"}, Mode: Sequential, Next: 1,
+				Decisions: []Decision{{Coordinator: "a", Approved: true}}}},
+			active: []string{"b"},
+		},
+		{
+			name:   "before approval modes, approved",
+			stored: bson.M{"coordinators": bson.A{"a", "b"}, "next": 1, "status": Approved},
+			want: []Stage{{Coordinators: []string{"a", "b"}, Mode: Sequential, Next: 1,
+				Decisions: []Decision{{Coordinator: "a", Approved: true}, {Coordinator: "b", Approved: true}}}},
+		},
+		{
+			name:   "before approval modes, declined",
+			stored: bson.M{"coordinators": bson.A{"a", "b"}, "next": 0, "status": Declined},
+			want: []Stage{{Coordinators: []string{"a", "b"}, Mode: Sequential,
+				Decisions: []Decision{{Coordinator: "a"}}}},
+		},
+		{
+			name: "with approval modes",
+			stored: bson.M{"coordinators": bson.A{"a", "b", "c"}, "mode": Quorum, "quorum": 2, "status": InProgress,
+				"decisions": bson.A{bson.M{"coordinator": "b", "approved": true}}},
+			want: []Stage{{Coordinators: []string{"a", "b", "c"}, Mode: Quorum, Quorum: 2,
+				Decisions: []Decision{{Coordinator: "b", Approved: true}}}},
+			active: []string{"a", "c"},
+		},
+		{
+			name: "with stages",
+			stored: bson.M{"coordinators": bson.A{"a"}, "status": NotStarted,
+				"stages": bson.A{bson.M{"coordinators": bson.A{"a"}, "mode": Parallel}}},
+			want:   []Stage{{Coordinators: []string{"a"}, Mode: Parallel}},
+			active: []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := bson.Marshal(tt.stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var task Task
+			if err = bson.Unmarshal(raw, &task); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(task.Stages, tt.want) {
+				t.Errorf("stages = %+v, want %+v", task.Stages, tt.want)
+			}
+			if got := task.Active(); !reflect.DeepEqual(got, tt.active) {
+				t.Errorf("Active() = %v, want %v", got, tt.active)
+			}
+		})
+	}
+}
+
+func TestSensitiveFields(t *testing.T) {
+	task := Task{
+		Name:        "Contract",
+		Description: "Draft",
+		Stages:      []Stage{{Coordinators: []string{"a"}, Decisions: []Decision{{Coordinator: "a", Comment: "typo"}}}},
+		Revisions: []Revision{{Name: "Old", Description: "Older",
+			Stages: []Stage{{Decisions: []Decision{{Coordinator: "a", Comment: "missing"}}}}}},
+	}
+	if err := task.Record(Event{Action: ActionDeclined, Comment: "missing"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Name, description, two decision comments, revision name and description, and the event comment
+	// both in History and among the recorded events.
+	if fields := task.SensitiveFields(); len(fields) != 8 {
+		t.Fatalf("SensitiveFields() returned %d fields, want 8", len(fields))
+	}
+
+	detached := task.Detached()
+	for _, field := range detached.SensitiveFields() {
+		*field = "sealed"
+	}
+	for _, field := range task.SensitiveFields() {
+		if *field == "sealed" {
+			t.Fatalf("Detached() shares fields with the task: %+v", task)
+		}
+	}
+	if comment := detached.Recorded()[0].Comment; comment != "sealed" {
+		t.Errorf("detached recorded comment = %q, want it sealed", comment)
+	}
+}
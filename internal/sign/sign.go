@@ -0,0 +1,47 @@
+// Package sign provides Ed25519 signatures of coordinator decisions.
+package sign
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+)
+
+const Algorithm = "Ed25519"
+
+var ErrInvalidKey = errors.New("signing key must be a base64 encoded Ed25519 seed")
+
+// Signer signs messages with a server-held Ed25519 key.
+type Signer struct {
+	key ed25519.PrivateKey
+}
+
+// NewSigner creates a Signer from a base64 encoded 32-byte Ed25519 seed.
+func NewSigner(seed string) (*Signer, error) {
+	decoded, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil || len(decoded) != ed25519.SeedSize {
+		return nil, ErrInvalidKey
+	}
+
+	return &Signer{key: ed25519.NewKeyFromSeed(decoded)}, nil
+}
+
+// Sign returns base64 encoded signature of msg.
+func (s *Signer) Sign(msg []byte) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, msg))
+}
+
+// PublicKey returns base64 encoded public key which verifies signatures of s.
+func (s *Signer) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+// Verify reports whether signature is a valid base64 encoded signature of msg by s.
+func (s *Signer) Verify(msg []byte, signature string) bool {
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	return ed25519.Verify(s.key.Public().(ed25519.PublicKey), msg, decoded)
+}
@@ -0,0 +1,55 @@
+package sign
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func TestSigner(t *testing.T) {
+	seed := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	signer, err := NewSigner(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewSigner(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{8}, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte(`{"task_id":"1","decision":"approved"}`)
+	signature := signer.Sign(msg)
+
+	tests := []struct {
+		name      string
+		signer    *Signer
+		msg       []byte
+		signature string
+		want      bool
+	}{
+		{name: "valid", signer: signer, msg: msg, signature: signature, want: true},
+		{name: "changed message", signer: signer, msg: []byte(`{"task_id":"1","decision":"declined"}`), signature: signature},
+		{name: "other key", signer: other, msg: msg, signature: signature},
+		{name: "not base64", signer: signer, msg: msg, signature: "%%%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.signer.Verify(tt.msg, tt.signature); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if signer.PublicKey() == other.PublicKey() {
+		t.Error("different seeds have the same public key")
+	}
+}
+
+func TestNewSignerInvalidKey(t *testing.T) {
+	for _, seed := range []string{"", "not base64", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, err := NewSigner(seed); err != ErrInvalidKey {
+			t.Errorf("NewSigner(%q): err = %v, want %v", seed, err, ErrInvalidKey)
+		}
+	}
+}
@@ -11,6 +11,9 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// RequestIDKey is the key of request ID in fiber.Ctx locals.
+const RequestIDKey = "requestid"
+
 type fiberLog struct {
 	RID        string
 	RemoteIP   string
@@ -65,6 +68,7 @@ func Middleware(logger Logger, filter func(ctx *fiber.Ctx) bool) fiber.Handler {
 			rid = uuid.New().String()
 			ctx.Set(fiber.HeaderXRequestID, rid)
 		}
+		ctx.Locals(RequestIDKey, rid)
 
 		event := &fiberLog{
 			RID:       rid,
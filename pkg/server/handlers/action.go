@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/config"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/server/response"
+	"html/template"
+	"net/url"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("action link is invalid")
+
+var ErrExpiredToken = errors.New("action link has expired")
+
+var ErrStaleToken = errors.New("action link is no longer valid for this task")
+
+// actionToken is the encrypted payload of a one-click link, bound to a single decision slot.
+type actionToken struct {
+	TaskID      string       `json:"t"`
+	Revision    int          `json:"r"`
+	Stage       int          `json:"s"`
+	Coordinator string       `json:"c"`
+	Delegate    string       `json:"d,omitempty"`
+	Action      model.Action `json:"a"`
+	Issued      int64        `json:"i"`
+	Expires     int64        `json:"e"`
+}
+
+// actionLink returns a one-click link to perform action on the current stage of task.
+func (h *TaskHandler) actionLink(task model.Task, coordinator, delegate string, action model.Action) (string, error) {
+	now := h.now()
+	payload, err := json.Marshal(actionToken{
+		TaskID:      task.ID.Hex(),
+		Revision:    task.Number(),
+		Stage:       task.Stage,
+		Coordinator: coordinator,
+		Delegate:    delegate,
+		Action:      action,
+		Issued:      now.UnixMilli(),
+		Expires:     now.Add(config.ActionInfo.TTL).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	token, err := h.Keyring.Encrypt(string(payload))
+	if err != nil {
+		return "", err
+	}
+
+	// Reason of a refusal can't be part of a link sent in advance, so it is asked for on a form.
+	if action == model.ActionDeclined && config.DeclineReasonRequired {
+		return fmt.Sprintf("%v/task/v1/action/decline?token=%v", config.PublicURL, url.QueryEscape(token)), nil
+	}
+
+	return fmt.Sprintf("%v/task/v1/action?token=%v", config.PublicURL, url.QueryEscape(token)), nil
+}
+
+// decodeActionToken decrypts and parses value of an action link.
+func (h *TaskHandler) decodeActionToken(value string) (actionToken, error) {
+	if h.Keyring == nil {
+		return actionToken{}, ErrInvalidToken
+	}
+
+	payload, err := h.Keyring.Decrypt(value)
+	if err != nil {
+		h.log.Debug(err)
+
+		return actionToken{}, ErrInvalidToken
+	}
+
+	var token actionToken
+	if err = json.Unmarshal([]byte(payload), &token); err != nil {
+		h.log.Debug(err)
+
+		return actionToken{}, ErrInvalidToken
+	}
+
+	if h.now().Unix() > token.Expires {
+		return actionToken{}, ErrExpiredToken
+	}
+
+	return token, nil
+}
+
+// DeclineForm
+// @Summary      Decline form
+// @Tags         Action
+// @Description  Decline form
+// @ID           decline-form
+// @Produce      html
+// @Param        token        query     string  true  "Action token"
+// @Success      200          {string}  string
+// @Failure      400,403,500  {object}  response.Error
+// @Router       /action/decline [get]
+func (h *TaskHandler) DeclineForm(ctx *fiber.Ctx) error {
+	token, err := h.decodeActionToken(ctx.Query("token"))
+	if errors.Is(err, ErrExpiredToken) {
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{Error: err.Error()})
+	} else if err != nil || token.Action != model.ActionDeclined {
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: ErrInvalidToken.Error()})
+	}
+
+	return h.Action(ctx)
+}
+
+// actionPage confirms a one-click decision, so that link scanners can't decide on the task.
+var actionPage = template.Must(template.New("action").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body>
+<form method="post" action="{{.Action}}">
+<input type="hidden" name="token" value="{{.Token}}">
+<p>{{.Title}} "{{.Name}}"{{if .Coordinator}} on behalf of {{.Coordinator}}{{end}}?</p>
+<p><label for="comment">{{if .Required}}Reason for declining the task:{{else}}Comment (optional):{{end}}</label></p>
+<p><textarea id="comment" name="comment" rows="6" cols="60" maxlength="{{.MaxLength}}"{{if .Required}} required{{end}}>{{.Comment}}</textarea></p>
+<p><button type="submit">{{.Button}}</button></p>
+</form>
+</body>
+</html>
+`))
+
+// Action
+// @Summary      Action
+// @Tags         Action
+// @Description  Confirm and apply one-click decision
+// @ID           action
+// @Accept       x-www-form-urlencoded
+// @Produce      json,html
+// @Param        token        query     string  true  "Action token"
+// @Param        comment      formData  string  false  "Comment on the approval or reason of the refusal"
+// @Success      200          {object}  response.Info
+// @Failure      400,403,409,500  {object}  response.Error
+// @Router       /action [get]
+// @Router       /action [post]
+func (h *TaskHandler) Action(ctx *fiber.Ctx) error {
+	value := ctx.FormValue("token")
+	token, err := h.decodeActionToken(value)
+	if errors.Is(err, ErrExpiredToken) {
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{Error: err.Error()})
+	} else if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+	}
+
+	task, err := h.Db.GetTaskById(ctx.UserContext(), token.TaskID)
+	if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusBadRequest, err, "unable to get task")
+	} else if task.Number() != token.Revision || task.Stage != token.Stage || !task.CanAct(token.Coordinator) ||
+		task.Unassigned(token.Stage, token.Coordinator, time.UnixMilli(token.Issued)) {
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{Error: ErrStaleToken.Error()})
+	}
+
+	actor := token.Coordinator
+	if token.Delegate != "" {
+		ok, err := h.actsFor(ctx.UserContext(), token.Delegate, token.Coordinator)
+		if err != nil {
+			return h.HandleDbError(ctx, fiber.StatusInternalServerError, err, "unable to get delegations")
+		} else if !ok {
+			return ctx.Status(fiber.StatusForbidden).JSON(response.Error{Error: ErrStaleToken.Error()})
+		}
+		actor = token.Delegate
+	}
+
+	if ctx.Method() == fiber.MethodGet {
+		return h.confirmAction(ctx, task, token, value)
+	}
+
+	comment, err := decisionComment(ctx, token.Action)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+	}
+
+	switch token.Action {
+	case model.ActionApproved:
+		return h.approve(ctx, task, token.Coordinator, actor, comment)
+	case model.ActionDeclined:
+		return h.decline(ctx, task, token.Coordinator, actor, comment)
+
+	default:
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: ErrInvalidToken.Error()})
+	}
+}
+
+// confirmAction responds with the confirmation page of the decision of token on task.
+func (h *TaskHandler) confirmAction(ctx *fiber.Ctx, task model.Task, token actionToken, value string) error {
+	page := struct {
+		Action      string
+		Token       string
+		Title       string
+		Button      string
+		Name        string
+		Coordinator string
+		Comment     string
+		Required    bool
+		MaxLength   int
+	}{
+		Action:    fmt.Sprintf("%v/task/v1/action", config.PublicURL),
+		Token:     value,
+		Title:     "Approve task",
+		Button:    "Approve",
+		Name:      task.Name,
+		Comment:   ctx.Query("comment"),
+		MaxLength: maxCommentLength,
+	}
+	if token.Delegate != "" {
+		page.Coordinator = token.Coordinator
+	}
+	if token.Action == model.ActionDeclined {
+		page.Title, page.Button = "Decline task", "Decline"
+		page.Required = config.DeclineReasonRequired
+	}
+
+	ctx.Type("html", "utf-8")
+
+	return actionPage.Execute(ctx, page)
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/config"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/server/request"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxCommentLength is the maximum number of characters in a decision comment.
+const maxCommentLength = 1000
+
+var ErrReasonRequired = errors.New("reason is required to decline the task")
+
+var ErrCommentTooLong = errors.New("comment must not be longer than 1000 characters")
+
+// decisionComment returns the comment given with action in the body or the query.
+func decisionComment(ctx *fiber.Ctx, action model.Action) (string, error) {
+	comment := ctx.Query("comment")
+	if len(ctx.Body()) > 0 {
+		var decisionRequest request.DecisionRequest
+		if err := ctx.BodyParser(&decisionRequest); err != nil {
+			return "", err
+		}
+		comment = decisionRequest.Comment
+	}
+
+	comment = strings.TrimSpace(comment)
+	switch {
+	case utf8.RuneCountInString(comment) > maxCommentLength:
+		return "", ErrCommentTooLong
+	case comment == "" && action == model.ActionDeclined && config.DeclineReasonRequired:
+		return "", ErrReasonRequired
+	}
+
+	return comment, nil
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/config"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/server/request"
+)
+
+func TestDecisionComments(t *testing.T) {
+	required := config.DeclineReasonRequired
+	t.Cleanup(func() { config.DeclineReasonRequired = required })
+
+	tests := []struct {
+		name     string
+		required bool
+		path     string
+		comment  string
+		want     int
+	}{
+		{name: "decline without reason", path: "/decline/a/", want: fiber.StatusOK},
+		{name: "required reason missing", required: true, path: "/decline/a/", want: fiber.StatusBadRequest},
+		{name: "required reason blank", required: true, path: "/decline/a/", comment: "  ", want: fiber.StatusBadRequest},
+		{name: "required reason given", required: true, path: "/decline/a/", comment: "too expensive", want: fiber.StatusOK},
+		{name: "approve without comment", required: true, path: "/approve/a/", want: fiber.StatusOK},
+		{name: "approve with comment", path: "/approve/a/", comment: "fine", want: fiber.StatusOK},
+		{name: "comment too long", path: "/approve/a/", comment: strings.Repeat("ы", maxCommentLength+1), want: fiber.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.DeclineReasonRequired = tt.required
+			s := newTestServer(t, nil)
+			id := s.add(t, request.AddRequest{Name: "Contract", Coordinators: []string{"a"}})
+
+			code, body := s.do(t, http.MethodPost, tt.path+id, "a", request.DecisionRequest{Comment: tt.comment})
+			if code != tt.want {
+				t.Fatalf("status %v %v, want %v", code, body, tt.want)
+			}
+			if code != fiber.StatusOK {
+				return
+			}
+
+			d := s.task(t, id).Stages[0].Decision("a")
+			if d == nil || d.Comment != strings.TrimSpace(tt.comment) {
+				t.Errorf("decision %+v, want comment %q", d, tt.comment)
+			}
+		})
+	}
+}
+
+func TestDeclineForm(t *testing.T) {
+	required := config.DeclineReasonRequired
+	t.Cleanup(func() { config.DeclineReasonRequired = required })
+	config.DeclineReasonRequired = true
+
+	s := newTestServer(t, nil)
+	id := s.add(t, request.AddRequest{Name: "Contract", Coordinators: []string{"a"}})
+
+	path, decline := s.actionTarget(t, id, "a", "", model.ActionDeclined)
+	if path != "/action/decline" {
+		t.Fatalf("decline link path %q, want /action/decline", path)
+	}
+	approve := s.actionToken(t, id, "a", "", model.ActionApproved)
+
+	code, body := s.do(t, http.MethodGet, "/action/decline?token="+url.QueryEscape(decline), "", nil)
+	if code != fiber.StatusOK || !strings.Contains(body, `method="post"`) || !strings.Contains(body, `required>`) {
+		t.Fatalf("form: status %v %v", code, body)
+	}
+	if code, body = s.do(t, http.MethodGet, "/action/decline?token="+url.QueryEscape(approve), "", nil); code != fiber.StatusBadRequest {
+		t.Errorf("form for an approval: status %v %v", code, body)
+	}
+	if task := s.task(t, id); task.Status != model.NotStarted {
+		t.Fatalf("status %v after opening the form, want %v", task.Status, model.NotStarted)
+	}
+
+	if code, body = s.postForm(t, "/action", url.Values{"token": {decline}, "comment": {" "}}); code != fiber.StatusBadRequest {
+		t.Errorf("decline without reason: status %v %v", code, body)
+	}
+	if code, body = s.postForm(t, "/action", url.Values{"token": {decline}, "comment": {"too expensive"}}); code != fiber.StatusOK {
+		t.Fatalf("decline: status %v %v", code, body)
+	}
+
+	task := s.task(t, id)
+	if d := task.Stages[0].Decision("a"); task.Status != model.Declined || d == nil || d.Comment != "too expensive" {
+		t.Errorf("status %v, decision %+v, want declined with the reason", task.Status, d)
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/mail-service/pkg/server/request"
+	"github.com/richard-on/mail-service/pkg/templates"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/server/response"
+	"strings"
+)
+
+// approve records approval of task by actor on behalf of coordinator. Caller authenticates actor.
+func (h *TaskHandler) approve(ctx *fiber.Ctx, task model.Task, coordinator, actor, comment string) error {
+	active, stage := task.Active(), task.Stage
+	event := h.newDecisionEvent(ctx, coordinator, actor, model.ActionApproved, stage)
+	event.Comment = comment
+	if err := task.Approve(coordinator, comment); err != nil {
+		return HandleTransitionError(ctx, task, err)
+	}
+	if err := h.signDecision(&task, stage, coordinator, actor, event.Time); err != nil {
+		h.log.Error(err, "unable to sign decision")
+		return ctx.SendStatus(fiber.StatusInternalServerError)
+	}
+	if err := task.Record(event); err != nil {
+		h.log.Error(err, "unable to record task history")
+		return ctx.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	err := h.Db.UpdateTask(ctx.UserContext(), &task)
+	if err != nil {
+		return h.HandleUpdateError(ctx, task.ID.Hex(), err)
+	}
+
+	if task.Status == model.Approved {
+		h.sendApprovedMail(ctx, actor, task)
+
+		return ctx.Status(fiber.StatusOK).JSON(response.Info{
+			Message: "coordination end: approved",
+		})
+	}
+
+	h.sendCoordinationMail(ctx, task.Initiator, task, newlyActive(active, task))
+	if comment != "" {
+		h.sendInfoMail(ctx, actor, task, []string{task.Initiator},
+			decisionNote("TASK APPROVED BY COORDINATOR!", stage, coordinator, actor, comment))
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.Info{
+		Message: fmt.Sprintf("you have approved this task%v: awaiting decision from: %v",
+			onBehalf(coordinator, actor), strings.Join(task.Active(), ", ")),
+	})
+}
+
+// sendApprovedMail tells coordinators of task that it has been approved, along with the comments they have given.
+func (h *TaskHandler) sendApprovedMail(ctx *fiber.Ctx, from string, task model.Task) {
+	body := "TASK VERIFIED!"
+	for i, stage := range task.Stages {
+		for _, d := range stage.Decisions {
+			if d.Comment != "" {
+				body += fmt.Sprintf(" Stage %v, %v: %v", i+1, d.Coordinator, d.Comment)
+			}
+		}
+	}
+
+	for _, v := range task.Coordinators {
+		sendReq := request.SendMail{
+			From:    from,
+			Subject: task.Description,
+			To:      v,
+			Type:    "info",
+			Template: templates.Info{
+				Body: body,
+			},
+		}
+
+		h.sendMail(ctx, sendReq)
+	}
+}
+
+// decline records refusal of task by actor on behalf of coordinator. Caller authenticates actor.
+func (h *TaskHandler) decline(ctx *fiber.Ctx, task model.Task, coordinator, actor, reason string) error {
+	stage := task.Stage
+	event := h.newDecisionEvent(ctx, coordinator, actor, model.ActionDeclined, stage)
+	event.Comment = reason
+	if err := task.Decline(coordinator, reason); err != nil {
+		return HandleTransitionError(ctx, task, err)
+	}
+	if err := h.signDecision(&task, stage, coordinator, actor, event.Time); err != nil {
+		h.log.Error(err, "unable to sign decision")
+		return ctx.SendStatus(fiber.StatusInternalServerError)
+	}
+	if err := task.Record(event); err != nil {
+		h.log.Error(err, "unable to record task history")
+		return ctx.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	err := h.Db.UpdateTask(ctx.UserContext(), &task)
+	if err != nil {
+		return h.HandleUpdateError(ctx, task.ID.Hex(), err)
+	}
+
+	if task.Status != model.Declined {
+		if reason != "" {
+			h.sendInfoMail(ctx, actor, task, []string{task.Initiator},
+				decisionNote("TASK DECLINED BY COORDINATOR!", stage, coordinator, actor, reason))
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(response.Info{
+			Message: fmt.Sprintf("you have declined this task%v: %v of %v required approvals are still reachable in this stage",
+				onBehalf(coordinator, actor), task.Current().Approvals()+len(task.Active()), task.Current().Required()),
+		})
+	}
+
+	h.sendInfoMail(ctx, actor, task, []string{task.Initiator},
+		decisionNote("TASK DECLINED!", stage, coordinator, actor, reason))
+
+	return ctx.Status(fiber.StatusOK).JSON(response.Info{
+		Message: "you have declined this task" + onBehalf(coordinator, actor),
+	})
+}
+
+// decisionNote is the body of an email telling the initiator about a decision.
+func decisionNote(headline string, stage int, coordinator, actor, comment string) string {
+	note := fmt.Sprintf("%v Stage %v has been decided by %v%v.", headline, stage+1, actor, onBehalf(coordinator, actor))
+	if comment != "" {
+		note += " Comment: " + comment
+	}
+
+	return note
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/internal/db"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/server/request"
+	"github.com/richard-on/task-service/pkg/server/response"
+	"time"
+)
+
+var ErrNoDelegate = errors.New("delegate is required")
+
+var ErrSelfDelegation = errors.New("you can't delegate to yourself")
+
+var ErrInvalidPeriod = errors.New("delegation must end after it starts and in the future")
+
+var ErrDelegationOverlap = errors.New("delegation overlaps with another delegation of yours")
+
+// AddDelegation
+// @Summary      Add delegation
+// @Tags         Delegation
+// @Description  Delegate approval authority
+// @ID           add-delegation
+// @Accept       json
+// @Produce      json
+// @Param        input    body      request.DelegationRequest  true  "Delegation"
+// @Success      200      {object}  model.Delegation
+// @Failure      400,403,409,500,504  {object}  response.Error
+// @Router       /delegations [post]
+func (h *TaskHandler) AddDelegation(ctx *fiber.Ctx) error {
+	validateResponse, err := h.authenticate(ctx)
+	if err != nil {
+		return h.HandleAuthError(ctx, err)
+	}
+
+	var delegationRequest request.DelegationRequest
+	if err = ctx.BodyParser(&delegationRequest); err != nil {
+		h.log.Debug(err, "parsing error")
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+	}
+
+	now := h.now().UTC().Truncate(time.Millisecond)
+	delegation := model.Delegation{
+		Delegator: validateResponse.Email,
+		Delegate:  delegationRequest.Delegate,
+		Start:     now,
+		End:       delegationRequest.End.UTC().Truncate(time.Millisecond),
+	}
+	if delegationRequest.Start != nil {
+		delegation.Start = delegationRequest.Start.UTC().Truncate(time.Millisecond)
+	}
+
+	switch {
+	case delegation.Delegate == "":
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: ErrNoDelegate.Error()})
+	case delegation.Delegate == delegation.Delegator:
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: ErrSelfDelegation.Error()})
+	case !delegation.End.After(delegation.Start) || !delegation.End.After(now):
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: ErrInvalidPeriod.Error()})
+	}
+
+	existing, err := h.Db.GetDelegations(ctx.UserContext(), delegation.Delegator)
+	if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusInternalServerError, err, "unable to get delegations")
+	}
+	for _, d := range existing {
+		if d.Delegator == delegation.Delegator && d.Overlaps(delegation) {
+			return ctx.Status(fiber.StatusConflict).JSON(response.Error{Error: ErrDelegationOverlap.Error()})
+		}
+	}
+
+	delegation, err = h.Db.AddDelegation(ctx.UserContext(), delegation)
+	if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusInternalServerError, err, "unable to add delegation")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(delegation)
+}
+
+// ListDelegations
+// @Summary      List delegations
+// @Tags         Delegation
+// @Description  List delegations
+// @ID           list-delegations
+// @Produce      json
+// @Success      200      {object}  response.Delegations
+// @Failure      403,500,504  {object}  response.Error
+// @Router       /delegations [get]
+func (h *TaskHandler) ListDelegations(ctx *fiber.Ctx) error {
+	validateResponse, err := h.authenticate(ctx)
+	if err != nil {
+		return h.HandleAuthError(ctx, err)
+	}
+
+	delegations, err := h.Db.GetDelegations(ctx.UserContext(), validateResponse.Email)
+	if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusInternalServerError, err, "unable to get delegations")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.Delegations{Delegations: delegations})
+}
+
+// DeleteDelegation
+// @Summary      Delete delegation
+// @Tags         Delegation
+// @Description  Revoke delegation
+// @ID           delete-delegation
+// @Produce      json
+// @Param        delegation_id  path      string  true  "Delegation ID"
+// @Success      200            {object}  response.Info
+// @Failure      400,403,404,500,504  {object}  response.Error
+// @Router       /delegations/:delegation_id [delete]
+func (h *TaskHandler) DeleteDelegation(ctx *fiber.Ctx) error {
+	validateResponse, err := h.authenticate(ctx)
+	if err != nil {
+		return h.HandleAuthError(ctx, err)
+	}
+
+	delegationId := ctx.Params("delegation_id")
+	err = h.Db.DeleteDelegation(ctx.UserContext(), delegationId, validateResponse.Email)
+	if errors.Is(err, db.ErrDelegationNotFound) {
+		return ctx.Status(fiber.StatusNotFound).JSON(response.Error{Error: err.Error()})
+	} else if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusBadRequest, err, "unable to delete delegation")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.Info{
+		Message: fmt.Sprintf("successfully deleted delegation %v", delegationId),
+	})
+}
+
+// delegateOf returns the delegate acting for coordinator at now or "" if coordinator acts in person.
+func (h *TaskHandler) delegateOf(ctx context.Context, coordinator string, now time.Time) (string, error) {
+	delegations, err := h.Db.GetDelegations(ctx, coordinator)
+	if err != nil {
+		return "", err
+	}
+
+	return model.ActiveDelegate(delegations, coordinator, now), nil
+}
+
+// actsFor reports whether email may decide for coordinator right now, in person or as a delegate.
+func (h *TaskHandler) actsFor(ctx context.Context, email, coordinator string) (bool, error) {
+	if email == coordinator {
+		return true, nil
+	}
+
+	delegate, err := h.delegateOf(ctx, coordinator, h.now())
+
+	return err == nil && delegate == email, err
+}
+
+// awaiting returns tasks on which email may act right now, in person or as a delegate.
+func (h *TaskHandler) awaiting(ctx context.Context, email string) ([]model.Task, error) {
+	tasks, err := h.Db.GetAwaitingTasks(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	delegations, err := h.Db.GetDelegations(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(tasks))
+	for _, t := range tasks {
+		seen[t.ID.Hex()] = true
+	}
+
+	now := h.now()
+	for _, d := range delegations {
+		if d.Delegate != email || !d.Active(now) {
+			continue
+		}
+
+		delegated, err := h.Db.GetAwaitingTasks(ctx, d.Delegator)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range delegated {
+			if !seen[t.ID.Hex()] {
+				seen[t.ID.Hex()] = true
+				tasks = append(tasks, t)
+			}
+		}
+	}
+
+	return tasks, nil
+}
+
+// onBehalf describes in whose name actor has decided, if it is not actor themselves.
+func onBehalf(coordinator, actor string) string {
+	if coordinator == actor {
+		return ""
+	}
+
+	return " on behalf of " + coordinator
+}
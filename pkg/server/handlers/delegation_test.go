@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/server/request"
+	"github.com/richard-on/task-service/pkg/server/response"
+)
+
+func TestAddDelegation(t *testing.T) {
+	s := newTestServer(t, nil)
+	now := time.Now()
+	start, end := now.Add(time.Hour), now.Add(48*time.Hour)
+
+	tests := []struct {
+		name string
+		user string
+		body request.DelegationRequest
+		want int
+	}{
+		{name: "anonymous", body: request.DelegationRequest{Delegate: "d", End: end}, want: fiber.StatusForbidden},
+		{name: "no delegate", user: "a", body: request.DelegationRequest{End: end}, want: fiber.StatusBadRequest},
+		{name: "to yourself", user: "a", body: request.DelegationRequest{Delegate: "a", End: end}, want: fiber.StatusBadRequest},
+		{name: "ended", user: "a", body: request.DelegationRequest{Delegate: "d", End: now.Add(-time.Hour)},
+			want: fiber.StatusBadRequest},
+		{name: "ends before start", user: "a", body: request.DelegationRequest{Delegate: "d", Start: &end, End: start},
+			want: fiber.StatusBadRequest},
+		{name: "scheduled", user: "a", body: request.DelegationRequest{Delegate: "d", Start: &start, End: end},
+			want: fiber.StatusOK},
+		{name: "overlapping", user: "a", body: request.DelegationRequest{Delegate: "e", End: start.Add(time.Minute)},
+			want: fiber.StatusConflict},
+		{name: "before the scheduled one", user: "a", body: request.DelegationRequest{Delegate: "e", End: start},
+			want: fiber.StatusOK},
+		{name: "of another delegator", user: "b", body: request.DelegationRequest{Delegate: "d", End: end},
+			want: fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, body := s.do(t, http.MethodPost, "/delegations", tt.user, tt.body); code != tt.want {
+				t.Errorf("status %v %v, want %v", code, body, tt.want)
+			}
+		})
+	}
+
+	code, body := s.do(t, http.MethodGet, "/delegations", "d", nil)
+	if code != fiber.StatusOK {
+		t.Fatalf("list: status %v %v", code, body)
+	}
+	var list response.Delegations
+	if err := json.Unmarshal([]byte(body), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Delegations) != 2 {
+		t.Errorf("delegations of d = %+v, want 2", list.Delegations)
+	}
+}
+
+func TestDelegatedDecision(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	code, body := s.do(t, http.MethodPost, "/delegations", "a", request.DelegationRequest{
+		Delegate: "d",
+		End:      time.Now().Add(time.Hour),
+	})
+	if code != fiber.StatusOK {
+		t.Fatalf("delegate: status %v %v", code, body)
+	}
+	var delegation model.Delegation
+	if err := json.Unmarshal([]byte(body), &delegation); err != nil {
+		t.Fatal(err)
+	}
+
+	id := s.add(t, request.AddRequest{Name: "Contract", Coordinators: []string{"a", "b"}, Mode: model.Parallel})
+	if to := s.mailer.recipients("coordination"); !reflect.DeepEqual(to, []string{"d", "b"}) {
+		t.Errorf("coordination emails to %v, want the delegate instead of a", to)
+	}
+
+	awaiting, err := s.handler.awaiting(context.Background(), "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(awaiting) != 1 || awaiting[0].ID.Hex() != id {
+		t.Errorf("tasks awaiting d = %+v, want the task of a", awaiting)
+	}
+
+	if code, body = s.do(t, http.MethodPost, "/approve/b/"+id, "d", nil); code != fiber.StatusForbidden {
+		t.Errorf("approve for a coordinator who did not delegate: status %v %v", code, body)
+	}
+	approve := s.actionToken(t, id, "a", "d", model.ActionApproved)
+
+	// Revoked delegation no longer lets the delegate act, in person or with a link.
+	if code, body = s.do(t, http.MethodDelete, "/delegations/"+delegation.ID.Hex(), "d", nil); code != fiber.StatusNotFound {
+		t.Errorf("delete by the delegate: status %v %v", code, body)
+	}
+	if code, body = s.do(t, http.MethodDelete, "/delegations/"+delegation.ID.Hex(), "a", nil); code != fiber.StatusOK {
+		t.Fatalf("delete: status %v %v", code, body)
+	}
+	if code, body = s.do(t, http.MethodPost, "/approve/a/"+id, "d", nil); code != fiber.StatusForbidden {
+		t.Errorf("approve after revocation: status %v %v", code, body)
+	}
+	if code, body = s.do(t, http.MethodPost, "/action?token="+url.QueryEscape(approve), "", nil); code != fiber.StatusForbidden {
+		t.Errorf("link after revocation: status %v %v", code, body)
+	}
+
+	if code, body = s.do(t, http.MethodPost, "/delegations", "a", request.DelegationRequest{
+		Delegate: "d",
+		End:      time.Now().Add(time.Hour),
+	}); code != fiber.StatusOK {
+		t.Fatalf("delegate again: status %v %v", code, body)
+	}
+	if code, body = s.do(t, http.MethodPost, "/approve/a/"+id, "d", nil); code != fiber.StatusOK {
+		t.Fatalf("approve on behalf: status %v %v", code, body)
+	}
+
+	task := s.task(t, id)
+	last := task.History[len(task.History)-1]
+	if last.Actor != "d" || last.Coordinator != "a" {
+		t.Errorf("event %+v, want d acting for a", last)
+	}
+	if d := task.Stages[0].Decision("a"); d == nil || !d.Approved {
+		t.Errorf("decision of a = %+v, want an approval", d)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"errors"
 	"github.com/gofiber/fiber/v2"
 	"github.com/richard-on/auth-service/pkg/response"
+	"github.com/richard-on/task-service/internal/db"
+	"github.com/richard-on/task-service/internal/model"
+	taskResponse "github.com/richard-on/task-service/pkg/server/response"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -30,6 +33,68 @@ func HandleGrpcError(ctx *fiber.Ctx, err error) error {
 	return err
 }
 
+// HandleAuthError responds to errors returned by TaskHandler.authenticate.
+func (h *TaskHandler) HandleAuthError(ctx *fiber.Ctx, err error) error {
+	h.log.Debug(err)
+
+	return ctx.Status(fiber.StatusForbidden).JSON(response.Error{Error: err.Error()})
+}
+
+// HandleTransitionError responds to errors returned by task lifecycle changes.
+func HandleTransitionError(ctx *fiber.Ctx, task model.Task, err error) error {
+	switch {
+	case errors.Is(err, model.ErrNotActive):
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{Error: ErrNoAccess.Error()})
+	case errors.Is(err, model.ErrOverdue):
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{Error: err.Error()})
+	case errors.Is(err, model.ErrInvalidTransition) && task.Finished():
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{Error: ErrAlreadyFinished.Error()})
+	case errors.Is(err, model.ErrInvalidTransition):
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{Error: err.Error()})
+
+	default:
+		return ctx.Status(fiber.StatusInternalServerError).JSON(response.Error{Error: err.Error()})
+	}
+}
+
+// HandleUpdateError responds to errors returned by db.UpdateTask, with the current task on conflict.
+func (h *TaskHandler) HandleUpdateError(ctx *fiber.Ctx, taskID string, err error) error {
+	if !errors.Is(err, db.ErrConflict) {
+		return h.HandleDbError(ctx, fiber.StatusInternalServerError, err, "unable to update task")
+	}
+
+	h.log.Debug(err)
+
+	task, err := h.Db.GetTaskById(ctx.UserContext(), taskID)
+	if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusInternalServerError, err, "unable to get task after conflict")
+	}
+
+	return ctx.Status(fiber.StatusConflict).JSON(taskResponse.Conflict{
+		Error: db.ErrConflict.Error(),
+		Task:  task,
+	})
+}
+
+// HandleDbError responds to errors returned by the task store, with 504 on timeout.
+func (h *TaskHandler) HandleDbError(ctx *fiber.Ctx, status int, err error, msg string) error {
+	switch {
+	case db.IsTimeout(err):
+		h.log.Error(err, msg)
+
+		return ctx.Status(fiber.StatusGatewayTimeout).JSON(response.Error{Error: ErrDbTimeout.Error()})
+	case status == fiber.StatusInternalServerError:
+		h.log.Error(err, msg)
+
+		return ctx.SendStatus(status)
+
+	default:
+		h.log.Debug(err)
+
+		return ctx.Status(status).JSON(response.Error{Error: err.Error()})
+	}
+}
+
 var ErrNoTasks = errors.New("no tasks found")
 
 var ErrNoCoordinators = errors.New("task must include at least one coordinator")
@@ -37,3 +102,15 @@ var ErrNoCoordinators = errors.New("task must include at least one coordinator")
 var ErrNoAccess = errors.New("you don't have a right to access this task")
 
 var ErrAlreadyFinished = errors.New("this task has been finished")
+
+var ErrInvalidMode = errors.New("unknown approval mode")
+
+var ErrInvalidEscalation = errors.New("SLA must not be negative and escalation must be one of notify, delegate or skip")
+
+var ErrNoEscalationContact = errors.New("escalation contact is required to notify or delegate on SLA breach")
+
+var ErrDueInPast = errors.New("due date must be in the future")
+
+var ErrInvalidQuorum = errors.New("quorum must be between 1 and the number of coordinators")
+
+var ErrDbTimeout = errors.New("database did not respond in time")
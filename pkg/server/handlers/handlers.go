@@ -2,33 +2,45 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/richard-on/auth-service/pkg/authService"
-	request2 "github.com/richard-on/mail-service/pkg/server/request"
-	"github.com/richard-on/mail-service/pkg/templates"
 	"github.com/richard-on/task-service/config"
 	"github.com/richard-on/task-service/internal/db"
+	encrypt "github.com/richard-on/task-service/internal/encrypt"
 	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/internal/sign"
 	"github.com/richard-on/task-service/pkg/logger"
 	"github.com/richard-on/task-service/pkg/server/request"
 	"github.com/richard-on/task-service/pkg/server/response"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
 )
 
 type TaskHandler struct {
 	Router      fiber.Router
 	AuthService authService.AuthServiceClient
-	Db          *db.DB
+	Db          db.Store
+	Signer      *sign.Signer
+	Keyring     *encrypt.Keyring
+	Mailer      Mailer
 	log         logger.Logger
+	now         func() time.Time
 }
 
-func NewTaskHandler(router fiber.Router, db *db.DB, authService authService.AuthServiceClient) *TaskHandler {
+// NewTaskHandler creates a TaskHandler. signer and keyring are optional.
+func NewTaskHandler(router fiber.Router, db db.Store, authService authService.AuthServiceClient,
+	signer *sign.Signer, keyring *encrypt.Keyring) *TaskHandler {
 	return &TaskHandler{
 		Router:      router,
 		AuthService: authService,
 		Db:          db,
+		Signer:      signer,
+		Keyring:     keyring,
+		Mailer:      MailService{URL: "http://localhost:3000/mail/v1/send", Timeout: 10 * time.Second},
 		log:         logger.NewLogger(config.DefaultWriter, config.LogInfo.Level, "task-handler"),
+		now:         time.Now,
 	}
 }
 
@@ -38,27 +50,52 @@ func NewTaskHandler(router fiber.Router, db *db.DB, authService authService.Auth
 // @Description  List tasks
 // @ID           list-tasks
 // @Produce      json
+// @Param        status        query     string  false  "Comma separated statuses"
+// @Param        coordinator   query     string  false  "Coordinator email"
+// @Param        created_from  query     string  false  "Created at or after, RFC 3339"
+// @Param        created_to    query     string  false  "Created before, RFC 3339"
+// @Param        updated_from  query     string  false  "Updated at or after, RFC 3339"
+// @Param        updated_to    query     string  false  "Updated before, RFC 3339"
+// @Param        name          query     string  false  "Name substring"
+// @Param        sort          query     string  false  "created_at, updated_at, name or status, prefixed with - for descending order"
+// @Param        limit         query     int     false  "Page size"
+// @Param        cursor        query     string  false  "next_cursor of the previous page"
 // @Success      200      {object}  handlers.ListResponse
 // @Failure      403,500  {object}  handlers.ErrorResponse
 // @Router       /tasks [get]
 func (h *TaskHandler) List(ctx *fiber.Ctx) error {
-	validateResponse, err := Validate(ctx)
+	validateResponse, err := h.authenticate(ctx)
 	if err != nil {
-		h.log.Error(err, "unable to get tasks")
-		return ctx.SendStatus(fiber.StatusInternalServerError)
+		return h.HandleAuthError(ctx, err)
+	}
+
+	var listRequest request.ListRequest
+	if err = ctx.QueryParser(&listRequest); err != nil {
+		h.log.Debug(err, "parsing error")
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
 	}
 
-	tasks, err := h.Db.GetAllTasks(validateResponse.Email)
+	query, err := newQuery(validateResponse.Email, listRequest)
 	if err != nil {
-		h.log.Error(err, "unable to get tasks")
-		return ctx.SendStatus(fiber.StatusInternalServerError)
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+	}
+
+	page, err := h.Db.ListTasks(ctx.UserContext(), query)
+	if errors.Is(err, db.ErrInvalidCursor) || errors.Is(err, db.ErrInvalidSort) || errors.Is(err, db.ErrEncryptedField) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+	} else if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusInternalServerError, err, "unable to get tasks")
 	}
 
-	if len(tasks) == 0 {
+	if page.Total == 0 {
 		return ctx.Status(fiber.StatusOK).JSON(response.Error{Error: ErrNoTasks.Error()})
 	}
 
-	return ctx.Status(fiber.StatusOK).JSON(response.ListResponse{Tasks: tasks})
+	return ctx.Status(fiber.StatusOK).JSON(response.ListResponse{
+		Tasks:      page.Tasks,
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	})
 }
 
 // Add
@@ -73,17 +110,9 @@ func (h *TaskHandler) List(ctx *fiber.Ctx) error {
 // @Failure      400,403,500  {object}  handlers.ErrorResponse
 // @Router       /add [post]
 func (h *TaskHandler) Add(ctx *fiber.Ctx) error {
-	validateRequest := &authService.ValidateRequest{
-		AccessToken:  ctx.Cookies("accessToken"),
-		RefreshToken: ctx.Cookies("refreshToken"),
-	}
-
-	// Check access token validity
-	validateResponse, err := h.AuthService.Validate(ctx.Context(), validateRequest)
+	validateResponse, err := h.authenticate(ctx)
 	if err != nil {
-		h.log.Debug(err)
-
-		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{Error: err.Error()})
+		return h.HandleAuthError(ctx, err)
 	}
 
 	var addRequest request.AddRequest
@@ -92,38 +121,43 @@ func (h *TaskHandler) Add(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
 	}
 
-	if len(addRequest.Coordinators) == 0 {
-		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: ErrNoCoordinators.Error()})
+	stages, coordinators, err := newStages(addRequest)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
 	}
 
-	task, err := h.Db.AddTask(model.Task{
+	var dueAt *time.Time
+	if addRequest.DueAt != nil {
+		due := addRequest.DueAt.UTC().Truncate(time.Millisecond)
+		if !due.After(h.now()) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: ErrDueInPast.Error()})
+		}
+		dueAt = &due
+	}
+
+	task := model.Task{
 		ID:           primitive.NewObjectID(),
 		Name:         addRequest.Name,
 		Description:  addRequest.Description,
 		Initiator:    validateResponse.Email,
-		Coordinators: addRequest.Coordinators,
-		Next:         0,
+		Coordinators: coordinators,
+		Stages:       stages,
+		Stage:        0,
 		Status:       model.NotStarted,
-	})
-	if err != nil {
-		h.log.Error(err, "unable to add task to database")
+		DueAt:        dueAt,
+		Revision:     1,
+	}
+	if err = task.Record(h.newEvent(ctx, validateResponse.Email, model.ActionCreated, 0)); err != nil {
+		h.log.Error(err, "unable to record task history")
 		return ctx.SendStatus(fiber.StatusInternalServerError)
 	}
 
-	sendReq := request2.SendMail{
-		From:    validateResponse.Email,
-		Subject: task.Description,
-		To:      task.Coordinators[task.Next],
-		Type:    "coordination",
-		Template: templates.Coordination{
-			AcceptLink: fmt.Sprintf("localhost:5000/task/v1/approve/%v/%v",
-				task.Coordinators[task.Next], task.ID.Hex()),
-			DeclineLink: fmt.Sprintf("localhost:5000/task/v1/decline/%v/%v",
-				task.Coordinators[task.Next], task.ID.Hex()),
-		},
+	task, err = h.Db.AddTask(ctx.UserContext(), task)
+	if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusInternalServerError, err, "unable to add task to database")
 	}
 
-	SendEmail(ctx, sendReq)
+	h.sendCoordinationMail(ctx, validateResponse.Email, task, task.Active())
 
 	return ctx.Status(fiber.StatusOK).JSON(response.AddResponse{
 		ID:           task.ID,
@@ -131,40 +165,33 @@ func (h *TaskHandler) Add(ctx *fiber.Ctx) error {
 		Name:         task.Name,
 		Description:  task.Description,
 		Coordinators: task.Coordinators,
+		Stages:       task.Stages,
 		Status:       task.Status,
+		DueAt:        task.DueAt,
+		Revision:     task.Number(),
 	})
 }
 
-// Delete
-// @Summary      Delete
-// @Tags         Delete
-// @Description  Delete model
-// @ID           delete-model
+// Withdraw
+// @Summary      Withdraw
+// @Tags         Withdraw
+// @Description  Withdraw task
+// @ID           withdraw
 // @Produce      json
 // @Param        task_id  path      string  true  "Task ID"
-// @Success      200      {object}  handlers.TaskResponse
-// @Failure      400,403,500  {object}  handlers.ErrorResponse
-// @Router       /delete:task_id [delete]
-func (h *TaskHandler) Delete(ctx *fiber.Ctx) error {
-	validateRequest := &authService.ValidateRequest{
-		AccessToken:  ctx.Cookies("accessToken"),
-		RefreshToken: ctx.Cookies("refreshToken"),
-	}
-
-	// Check access token validity
-	validateResponse, err := h.AuthService.Validate(ctx.Context(), validateRequest)
+// @Success      200      {object}  response.Info
+// @Failure      400,403,409,500,504  {object}  response.Error
+// @Router       /tasks/:task_id/withdraw [post]
+func (h *TaskHandler) Withdraw(ctx *fiber.Ctx) error {
+	validateResponse, err := h.authenticate(ctx)
 	if err != nil {
-		h.log.Debug(err)
-
-		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{Error: err.Error()})
+		return h.HandleAuthError(ctx, err)
 	}
 
 	taskId := ctx.Params("task_id")
-	task, err := h.Db.GetTaskById(taskId)
+	task, err := h.Db.GetTaskById(ctx.UserContext(), taskId)
 	if err != nil {
-		h.log.Debug(err)
-
-		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+		return h.HandleDbError(ctx, fiber.StatusBadRequest, err, "unable to get task")
 	}
 	if validateResponse.Email != task.Initiator {
 		h.log.Debug(ErrNoAccess)
@@ -174,101 +201,119 @@ func (h *TaskHandler) Delete(ctx *fiber.Ctx) error {
 		})
 	}
 
-	err = h.Db.DeleteTask(taskId)
+	if err = task.Withdraw(); err != nil {
+		return HandleTransitionError(ctx, task, err)
+	}
+	if err = task.Record(h.newEvent(ctx, validateResponse.Email, model.ActionWithdrawn, task.Stage)); err != nil {
+		h.log.Error(err, "unable to record task history")
+		return ctx.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	err = h.Db.UpdateTask(ctx.UserContext(), &task)
 	if err != nil {
-		h.log.Error(err, "unable to delete task")
+		return h.HandleUpdateError(ctx, taskId, err)
+	}
 
-		return ctx.SendStatus(fiber.StatusInternalServerError)
+	h.sendInfoMail(ctx, task.Initiator, task, task.Reached(),
+		"TASK WITHDRAWN! Your decision on this task is no longer needed")
+
+	return ctx.Status(fiber.StatusOK).JSON(response.Info{
+		Message: fmt.Sprintf("successfully withdrew task %v", taskId),
+	})
+}
+
+// Purge
+// @Summary      Purge
+// @Tags         Admin
+// @Description  Purge task
+// @ID           purge
+// @Produce      json
+// @Param        task_id  path      string  true  "Task ID"
+// @Success      200      {object}  response.Info
+// @Failure      400,403,404,500,504  {object}  response.Error
+// @Router       /admin/tasks/:task_id [delete]
+func (h *TaskHandler) Purge(ctx *fiber.Ctx) error {
+	validateResponse, err := h.authenticate(ctx)
+	if err != nil {
+		return h.HandleAuthError(ctx, err)
+	}
+
+	if !isAdmin(validateResponse.Email) {
+		h.log.Debug(ErrNoAccess)
+
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{
+			Error: ErrNoAccess.Error(),
+		})
+	}
+
+	taskId := ctx.Params("task_id")
+	err = h.Db.DeleteTask(ctx.UserContext(), taskId)
+	if errors.Is(err, db.ErrNotFound) {
+		return ctx.Status(fiber.StatusNotFound).JSON(response.Error{Error: err.Error()})
+	} else if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusBadRequest, err, "unable to purge task")
 	}
+	h.log.Infof("task %v purged by %v", taskId, validateResponse.Email)
 
 	return ctx.Status(fiber.StatusOK).JSON(response.Info{
-		Message: fmt.Sprintf("successfully deleted task %v", taskId),
+		Message: fmt.Sprintf("successfully purged task %v", taskId),
 	})
 }
 
+// isAdmin reports whether email is listed in config.Admins.
+func isAdmin(email string) bool {
+	for _, admin := range config.Admins {
+		if admin == email {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Approve is an endpoint to approve.
 // @Summary      Approve
 // @Tags         Approve
 // @Description  Approve model
 // @ID           approve-model
+// @Accept       json
 // @Produce      json
-// @Param        task_id        path      string  true  "Task ID"
-// @Param        approvalLogin  path      string  true  "Approval login"
+// @Param        task_id        path      string                   true   "Task ID"
+// @Param        approvalLogin  path      string                   true   "Approval login"
+// @Param        input          body      request.DecisionRequest  false  "Comment on the approval"
 // @Success      200            {object}  handlers.TaskResponse
 // @Failure      400,403,500        {object}  handlers.ErrorResponse
 // @Router       /approve/:coordinator\:task_id [post]
 func (h *TaskHandler) Approve(ctx *fiber.Ctx) error {
-	validateRequest := &authService.ValidateRequest{
-		AccessToken:  ctx.Cookies("accessToken"),
-		RefreshToken: ctx.Cookies("refreshToken"),
+	validateResponse, err := h.authenticate(ctx)
+	if err != nil {
+		return h.HandleAuthError(ctx, err)
 	}
 
-	// Check access token validity
-	validateResponse, err := h.AuthService.Validate(ctx.Context(), validateRequest)
+	comment, err := decisionComment(ctx, model.ActionApproved)
 	if err != nil {
-		h.log.Debug(err)
-
-		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{Error: err.Error()})
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
 	}
 
 	coordinator := ctx.Params("coordinator")
 	taskID := ctx.Params("task_id")
-	task, err := h.Db.GetTaskById(taskID)
+	task, err := h.Db.GetTaskById(ctx.UserContext(), taskID)
 	if err != nil {
-		h.log.Debug(err)
+		return h.HandleDbError(ctx, fiber.StatusBadRequest, err, "unable to get task")
+	}
 
-		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
-	} else if validateResponse.Email != task.Coordinators[task.Next] || validateResponse.Email != coordinator {
+	// Delegates decide on behalf of the coordinator whose authority they hold.
+	if ok, err := h.actsFor(ctx.UserContext(), validateResponse.Email, coordinator); err != nil {
+		return h.HandleDbError(ctx, fiber.StatusInternalServerError, err, "unable to get delegations")
+	} else if !ok {
 		h.log.Debug(ErrNoAccess)
 
 		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{
 			Error: ErrNoAccess.Error(),
 		})
-	} else if task.Status != model.InProgress && task.Status != model.NotStarted {
-		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{
-			Error: ErrAlreadyFinished.Error(),
-		})
-	}
-
-	if task.Next+1 < len(task.Coordinators) {
-		task.Next = task.Next + 1
-		task.Status = model.InProgress
-	} else {
-		task.Status = model.Approved
-	}
-
-	err = h.Db.UpdateTask(&task)
-	if err != nil {
-		h.log.Error(err, "unable to update task")
-
-		return ctx.SendStatus(fiber.StatusInternalServerError)
-	}
-
-	if task.Status == model.Approved {
-
-		for _, v := range task.Coordinators {
-			sendReq := request2.SendMail{
-				From:    validateResponse.Email,
-				Subject: task.Description,
-				To:      v,
-				Type:    "info",
-				Template: templates.Info{
-					Body: "TASK VERIFIED!",
-				},
-			}
-
-			SendEmail(ctx, sendReq)
-		}
-
-		return ctx.Status(fiber.StatusOK).JSON(response.Info{
-			Message: "coordination end: approved",
-		})
 	}
 
-	return ctx.Status(fiber.StatusOK).JSON(response.Info{
-		Message: fmt.Sprintf("you have approved this task: next coordinator: %v",
-			task.Coordinators[task.Next]),
-	})
+	return h.approve(ctx, task, coordinator, validateResponse.Email, comment)
 }
 
 // Decline task
@@ -276,57 +321,44 @@ func (h *TaskHandler) Approve(ctx *fiber.Ctx) error {
 // @Tags         Decline
 // @Description  Decline task
 // @ID           decline
+// @Accept       json
 // @Produce      json
-// @Param        task_id        path      string  true  "Task ID"
-// @Param        approvalLogin  path      string  true  "Approval login"
+// @Param        task_id        path      string                   true   "Task ID"
+// @Param        approvalLogin  path      string                   true   "Approval login"
+// @Param        input          body      request.DecisionRequest  false  "Reason of the refusal, required if configured"
 // @Success      200            {object}  handlers.TaskResponse
 // @Failure      400,403,500        {object}  handlers.ErrorResponse
 // @Router       /decline/:coordinator\:task_id [post]
 func (h *TaskHandler) Decline(ctx *fiber.Ctx) error {
-	validateRequest := &authService.ValidateRequest{
-		AccessToken:  ctx.Cookies("accessToken"),
-		RefreshToken: ctx.Cookies("refreshToken"),
+	validateResponse, err := h.authenticate(ctx)
+	if err != nil {
+		return h.HandleAuthError(ctx, err)
 	}
 
-	// Check access token validity
-	validateResponse, err := h.AuthService.Validate(ctx.Context(), validateRequest)
+	comment, err := decisionComment(ctx, model.ActionDeclined)
 	if err != nil {
-		h.log.Debug(err)
-
-		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{Error: err.Error()})
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
 	}
 
 	coordinator := ctx.Params("coordinator")
 	taskID := ctx.Params("task_id")
-	task, err := h.Db.GetTaskById(taskID)
+	task, err := h.Db.GetTaskById(ctx.UserContext(), taskID)
 	if err != nil {
-		h.log.Debug(err)
+		return h.HandleDbError(ctx, fiber.StatusBadRequest, err, "unable to get task")
+	}
 
-		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
-	} else if validateResponse.Email != task.Coordinators[task.Next] || validateResponse.Email != coordinator {
+	// Delegates decide on behalf of the coordinator whose authority they hold.
+	if ok, err := h.actsFor(ctx.UserContext(), validateResponse.Email, coordinator); err != nil {
+		return h.HandleDbError(ctx, fiber.StatusInternalServerError, err, "unable to get delegations")
+	} else if !ok {
 		h.log.Debug(ErrNoAccess)
 
 		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{
 			Error: ErrNoAccess.Error(),
 		})
-	} else if task.Status != model.InProgress && task.Status != model.NotStarted {
-		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{
-			Error: ErrAlreadyFinished.Error(),
-		})
 	}
 
-	task.Status = model.Declined
-
-	err = h.Db.UpdateTask(&task)
-	if err != nil {
-		h.log.Error(err, "unable to update task")
-
-		return ctx.SendStatus(fiber.StatusInternalServerError)
-	}
-
-	return ctx.Status(fiber.StatusOK).JSON(response.Info{
-		Message: "you have declined this task",
-	})
+	return h.decline(ctx, task, coordinator, validateResponse.Email, comment)
 }
 
 // Run
@@ -0,0 +1,567 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/auth-service/pkg/authService"
+	mailRequest "github.com/richard-on/mail-service/pkg/server/request"
+	"github.com/richard-on/task-service/config"
+	"github.com/richard-on/task-service/internal/db"
+	encrypt "github.com/richard-on/task-service/internal/encrypt"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/internal/sign"
+	"github.com/richard-on/task-service/pkg/server/request"
+	"github.com/richard-on/task-service/pkg/server/response"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeAuth accepts any access token and treats it as the email of the caller.
+type fakeAuth struct {
+	authService.AuthServiceClient
+}
+
+func (fakeAuth) Validate(_ context.Context, in *authService.ValidateRequest, _ ...grpc.CallOption) (*authService.ValidateResponse, error) {
+	if in.AccessToken == "" {
+		return nil, status.Error(codes.Unauthenticated, "no access token")
+	}
+
+	// Cookie values share the request buffer, while a real response is decoded into its own memory.
+	return &authService.ValidateResponse{Email: strings.Clone(in.AccessToken)}, nil
+}
+
+// stubMailer records sent emails instead of posting them to the mail service.
+type stubMailer struct {
+	mu   sync.Mutex
+	sent []mailRequest.SendMail
+}
+
+func (m *stubMailer) Send(mailReq mailRequest.SendMail, _, _ string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = append(m.sent, mailReq)
+
+	return nil
+}
+
+// recipients returns addressees of the emails of the given type sent so far.
+func (m *stubMailer) recipients(mailType string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var to []string
+	for _, mail := range m.sent {
+		if mail.Type == mailType {
+			to = append(to, mail.To)
+		}
+	}
+
+	return to
+}
+
+// clock is a time source which only moves when it is advanced.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+type testServer struct {
+	app     *fiber.App
+	handler *TaskHandler
+	store   db.Store
+	mailer  *stubMailer
+	clock   *clock
+}
+
+// newTestServer serves task endpoints backed by store, or an in-memory store if it is nil.
+func newTestServer(t *testing.T, store db.Store) *testServer {
+	t.Helper()
+
+	ttl, timeout := config.ActionInfo.TTL, config.DbTimeout
+	t.Cleanup(func() { config.ActionInfo.TTL, config.DbTimeout = ttl, timeout })
+	config.ActionInfo.TTL = time.Hour
+	config.DbTimeout = time.Second
+
+	keyring, err := encrypt.NewKeyring(encrypt.Key{ID: "test", Secret: bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := sign.NewSigner(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if store == nil {
+		store = db.NewMemory()
+	}
+	mailer := &stubMailer{}
+	clock := &clock{now: time.Now().UTC().Truncate(time.Millisecond)}
+	app := fiber.New()
+	handler := NewTaskHandler(app, store, fakeAuth{}, signer, keyring)
+	handler.Mailer = mailer
+	handler.now = clock.Now
+
+	app.Get("/tasks", handler.List)
+	app.Get("/tasks/inbox", handler.Inbox)
+	app.Get("/tasks/participated", handler.Participated)
+	app.Get("/tasks/:task_id/stages/:stage/receipts/:coordinator", handler.Receipt)
+	app.Post("/add", handler.Add)
+	app.Post("/tasks/:task_id/withdraw", handler.Withdraw)
+	app.Post("/tasks/:task_id/resubmit", handler.Resubmit)
+	app.Post("/tasks/:task_id/resume", handler.Resume)
+	app.Get("/tasks/:task_id/revisions", handler.Revisions)
+	app.Get("/tasks/:task_id/revisions/diff", handler.RevisionDiff)
+	app.Put("/tasks/:task_id/stages/:stage/coordinators", handler.Reassign)
+	app.Delete("/admin/tasks/:task_id", handler.Purge)
+	app.Post("/approve/:coordinator/:task_id", handler.Approve)
+	app.Post("/decline/:coordinator/:task_id", handler.Decline)
+	app.Post("/return/:coordinator/:task_id", handler.Return)
+	app.Get("/action", handler.Action)
+	app.Post("/action", handler.Action)
+	app.Get("/action/decline", handler.DeclineForm)
+	app.Post("/delegations", handler.AddDelegation)
+	app.Get("/delegations", handler.ListDelegations)
+	app.Delete("/delegations/:delegation_id", handler.DeleteDelegation)
+
+	return &testServer{app: app, handler: handler, store: store, mailer: mailer, clock: clock}
+}
+
+// do sends a request on behalf of user, or anonymously if user is empty, and returns the response status and body.
+func (s *testServer) do(t *testing.T, method, target, user string, body interface{}) (int, string) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req := httptest.NewRequest(method, target, reader)
+	if body != nil {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+	if user != "" {
+		req.AddCookie(&http.Cookie{Name: "accessToken", Value: user})
+	}
+
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode, string(respBody)
+}
+
+// postForm posts form anonymously, as a browser submits an HTML form, and returns the response status and body.
+func (s *testServer) postForm(t *testing.T, target string, form url.Values) (int, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode, string(respBody)
+}
+
+// add creates a task initiated by "initiator" and returns its ID.
+func (s *testServer) add(t *testing.T, addRequest request.AddRequest) string {
+	t.Helper()
+
+	code, body := s.do(t, http.MethodPost, "/add", "initiator", addRequest)
+	if code != fiber.StatusOK {
+		t.Fatalf("add: %v %v", code, body)
+	}
+
+	var added response.AddResponse
+	if err := json.Unmarshal([]byte(body), &added); err != nil {
+		t.Fatal(err)
+	}
+
+	return added.ID.Hex()
+}
+
+func (s *testServer) task(t *testing.T, id string) model.Task {
+	t.Helper()
+
+	task, err := s.store.GetTaskById(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return task
+}
+
+// actionToken returns the token of the one-click link for action, sent to delegate if it is set.
+func (s *testServer) actionToken(t *testing.T, id, coordinator, delegate string, action model.Action) string {
+	t.Helper()
+
+	_, token := s.actionTarget(t, id, coordinator, delegate, action)
+
+	return token
+}
+
+// actionTarget returns the path of the one-click link for action relative to the service prefix and its token.
+func (s *testServer) actionTarget(t *testing.T, id, coordinator, delegate string, action model.Action) (string, string) {
+	t.Helper()
+
+	link, err := s.handler.actionLink(s.task(t, id), coordinator, delegate, action)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.TrimPrefix(u.Path, "/task/v1"), u.Query().Get("token")
+}
+
+func TestList(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.add(t, request.AddRequest{Name: "Contract", Coordinators: []string{"a"}})
+
+	if code, body := s.do(t, http.MethodGet, "/tasks", "", nil); code != fiber.StatusForbidden {
+		t.Errorf("anonymous: status %v %v", code, body)
+	}
+
+	code, body := s.do(t, http.MethodGet, "/tasks", "initiator", nil)
+	if code != fiber.StatusOK {
+		t.Fatalf("status %v %v", code, body)
+	}
+	var list response.ListResponse
+	if err := json.Unmarshal([]byte(body), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Tasks) != 1 || list.Tasks[0].Name != "Contract" {
+		t.Errorf("tasks = %+v, want the added task", list.Tasks)
+	}
+}
+
+func TestAddValidation(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	tests := []struct {
+		name string
+		user string
+		body request.AddRequest
+		want int
+	}{
+		{
+			name: "anonymous",
+			body: request.AddRequest{Name: "n", Coordinators: []string{"a"}},
+			want: fiber.StatusForbidden,
+		},
+		{
+			name: "no coordinators",
+			user: "initiator",
+			body: request.AddRequest{Name: "n"},
+			want: fiber.StatusBadRequest,
+		},
+		{
+			name: "duplicate coordinator",
+			user: "initiator",
+			body: request.AddRequest{Name: "n", Coordinators: []string{"a", "b", "a"}},
+			want: fiber.StatusBadRequest,
+		},
+		{
+			name: "unknown mode",
+			user: "initiator",
+			body: request.AddRequest{Name: "n", Coordinators: []string{"a"}, Mode: "unanimous"},
+			want: fiber.StatusBadRequest,
+		},
+		{
+			name: "unreachable quorum",
+			user: "initiator",
+			body: request.AddRequest{Name: "n", Coordinators: []string{"a", "b"}, Mode: model.Quorum, Quorum: 3},
+			want: fiber.StatusBadRequest,
+		},
+		{
+			name: "escalation without contact",
+			user: "initiator",
+			body: request.AddRequest{Name: "n", Coordinators: []string{"a"}, SLA: model.Duration(time.Hour)},
+			want: fiber.StatusBadRequest,
+		},
+		{
+			name: "unknown escalation policy",
+			user: "initiator",
+			body: request.AddRequest{Name: "n", Coordinators: []string{"a"}, SLA: model.Duration(time.Hour),
+				Escalation: "ignore", EscalateTo: "boss"},
+			want: fiber.StatusBadRequest,
+		},
+		{
+			name: "skip without contact",
+			user: "initiator",
+			body: request.AddRequest{Name: "n", Coordinators: []string{"a"}, SLA: model.Duration(time.Hour),
+				Escalation: model.EscalateSkip},
+			want: fiber.StatusOK,
+		},
+		{
+			name: "same coordinator in two stages",
+			user: "initiator",
+			body: request.AddRequest{Name: "n", Stages: []request.StageRequest{
+				{Coordinators: []string{"a"}},
+				{Coordinators: []string{"a"}},
+			}},
+			want: fiber.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, body := s.do(t, http.MethodPost, "/add", tt.user, tt.body); code != tt.want {
+				t.Errorf("status %v %v, want %v", code, body, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecisions(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	id := s.add(t, request.AddRequest{Name: "Contract", Stages: []request.StageRequest{
+		{Coordinators: []string{"a", "b"}},
+		{Coordinators: []string{"c"}},
+	}})
+	if to := s.mailer.recipients("coordination"); len(to) != 1 || to[0] != "a" {
+		t.Fatalf("coordination emails to %v, want a", to)
+	}
+
+	steps := []struct {
+		name   string
+		target string
+		user   string
+		want   int
+	}{
+		{name: "out of turn", target: "/approve/b/" + id, user: "b", want: fiber.StatusForbidden},
+		{name: "on behalf of another coordinator", target: "/approve/a/" + id, user: "b", want: fiber.StatusForbidden},
+		{name: "approve", target: "/approve/a/" + id, user: "a", want: fiber.StatusOK},
+		{name: "decline", target: "/decline/b/" + id, user: "b", want: fiber.StatusOK},
+		{name: "approve declined task", target: "/approve/c/" + id, user: "c", want: fiber.StatusForbidden},
+	}
+	for _, step := range steps {
+		if code, body := s.do(t, http.MethodPost, step.target, step.user, nil); code != step.want {
+			t.Fatalf("%v: status %v %v, want %v", step.name, code, body, step.want)
+		}
+	}
+
+	task := s.task(t, id)
+	if task.Status != model.Declined || task.Stage != 0 {
+		t.Fatalf("status %v at stage %v, want %v at stage 0", task.Status, task.Stage, model.Declined)
+	}
+	if broken, err := task.VerifyHistory(); err != nil || broken != -1 || len(task.History) != 3 {
+		t.Errorf("VerifyHistory() = %v, %v of %v events, want an intact chain of 3", broken, err, len(task.History))
+	}
+}
+
+func TestReceipt(t *testing.T) {
+	s := newTestServer(t, nil)
+	id := s.add(t, request.AddRequest{Name: "Contract", Coordinators: []string{"a", "b"}, Mode: model.Parallel})
+
+	if code, body := s.do(t, http.MethodPost, "/approve/a/"+id, "a", nil); code != fiber.StatusOK {
+		t.Fatalf("approve: status %v %v", code, body)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		user   string
+		want   int
+	}{
+		{name: "anonymous", target: "/tasks/" + id + "/stages/0/receipts/a", want: fiber.StatusForbidden},
+		{name: "outsider", target: "/tasks/" + id + "/stages/0/receipts/a", user: "x", want: fiber.StatusForbidden},
+		{name: "undecided coordinator", target: "/tasks/" + id + "/stages/0/receipts/b", user: "initiator",
+			want: fiber.StatusNotFound},
+		{name: "missing stage", target: "/tasks/" + id + "/stages/1/receipts/a", user: "initiator",
+			want: fiber.StatusNotFound},
+		{name: "decision", target: "/tasks/" + id + "/stages/0/receipts/a", user: "b", want: fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, body := s.do(t, http.MethodGet, tt.target, tt.user, nil); code != tt.want {
+				t.Errorf("status %v %v, want %v", code, body, tt.want)
+			}
+		})
+	}
+
+	code, body := s.do(t, http.MethodGet, "/tasks/"+id+"/stages/0/receipts/a", "initiator", nil)
+	if code != fiber.StatusOK {
+		t.Fatalf("status %v %v", code, body)
+	}
+	var receipt response.Receipt
+	if err := json.Unmarshal([]byte(body), &receipt); err != nil {
+		t.Fatal(err)
+	}
+	if !receipt.Valid || receipt.ContentChanged || receipt.Statement.Decision != model.ActionApproved {
+		t.Errorf("receipt = %+v, want a valid approval of unchanged content", receipt)
+	}
+	if !s.handler.Signer.Verify([]byte(receipt.Canonical), receipt.Signature) {
+		t.Error("receipt signature does not verify")
+	}
+}
+
+func TestAction(t *testing.T) {
+	s := newTestServer(t, nil)
+	id := s.add(t, request.AddRequest{Name: "Contract", Coordinators: []string{"a", "b"}, Mode: model.Parallel})
+
+	approveA := s.actionToken(t, id, "a", "", model.ActionApproved)
+	declineA := s.actionToken(t, id, "a", "", model.ActionDeclined)
+	approveB := s.actionToken(t, id, "b", "", model.ActionApproved)
+
+	steps := []struct {
+		name   string
+		method string
+		token  string
+		want   int
+	}{
+		{name: "invalid token", method: http.MethodGet, token: "invalid", want: fiber.StatusBadRequest},
+		{name: "confirmation page", method: http.MethodGet, token: approveA, want: fiber.StatusOK},
+		{name: "confirmation page opened again", method: http.MethodGet, token: approveA, want: fiber.StatusOK},
+		{name: "approve", method: http.MethodPost, token: approveA, want: fiber.StatusOK},
+		{name: "replayed token", method: http.MethodPost, token: approveA, want: fiber.StatusForbidden},
+		{name: "confirmation page of a used token", method: http.MethodGet, token: approveA, want: fiber.StatusForbidden},
+		{name: "other decision of the same coordinator", method: http.MethodPost, token: declineA, want: fiber.StatusForbidden},
+	}
+	for _, step := range steps {
+		if code, body := s.do(t, step.method, "/action?token="+url.QueryEscape(step.token), "", nil); code != step.want {
+			t.Fatalf("%v: status %v %v, want %v", step.name, code, body, step.want)
+		}
+	}
+
+	code, body := s.do(t, http.MethodGet, "/action?token="+url.QueryEscape(approveB), "", nil)
+	if code != fiber.StatusOK || !strings.Contains(body, `method="post"`) || !strings.Contains(body, `name="token"`) {
+		t.Fatalf("confirmation page: status %v %v", code, body)
+	}
+	if task := s.task(t, id); task.Status != model.InProgress {
+		t.Fatalf("status %v after opening the confirmation page, want %v", task.Status, model.InProgress)
+	}
+
+	// The confirmation page posts the token and the comment as a form.
+	if code, body = s.postForm(t, "/action", url.Values{"token": {approveB}, "comment": {"fine"}}); code != fiber.StatusOK {
+		t.Fatalf("posted form: status %v %v", code, body)
+	}
+
+	task := s.task(t, id)
+	if d := task.Stages[0].Decision("b"); task.Status != model.Approved || d == nil || d.Comment != "fine" {
+		t.Errorf("status %v, decision %+v, want approved with the comment", task.Status, d)
+	}
+}
+
+func TestActionReassigned(t *testing.T) {
+	s := newTestServer(t, nil)
+	id := s.add(t, request.AddRequest{Name: "Contract", Coordinators: []string{"a", "b"}, Mode: model.Parallel})
+
+	approveB := s.actionToken(t, id, "b", "", model.ActionApproved)
+
+	// Coordinator taken off the stage and put back gets a new link, the old one stays unusable.
+	s.clock.Advance(time.Millisecond)
+	for _, coordinators := range [][]string{{"a", "c"}, {"a", "b"}} {
+		code, body := s.do(t, http.MethodPut, "/tasks/"+id+"/stages/0/coordinators", "initiator",
+			request.ReassignRequest{Coordinators: coordinators})
+		if code != fiber.StatusOK {
+			t.Fatalf("reassign to %v: status %v %v", coordinators, code, body)
+		}
+	}
+	if code, body := s.do(t, http.MethodPost, "/action?token="+url.QueryEscape(approveB), "", nil); code != fiber.StatusForbidden {
+		t.Errorf("token issued before removal: status %v %v", code, body)
+	}
+
+	// A link issued in the same millisecond as the reassignment stays valid.
+	approveB = s.actionToken(t, id, "b", "", model.ActionApproved)
+	if code, body := s.do(t, http.MethodPost, "/action?token="+url.QueryEscape(approveB), "", nil); code != fiber.StatusOK {
+		t.Fatalf("approve with a new token: status %v %v", code, body)
+	}
+}
+
+// racingStore modifies each task right before its first update, as a concurrent request would.
+type racingStore struct {
+	*db.Memory
+	raced map[string]bool
+}
+
+func (s *racingStore) UpdateTask(ctx context.Context, task *model.Task) error {
+	id := task.ID.Hex()
+	if !s.raced[id] {
+		s.raced[id] = true
+
+		concurrent, err := s.Memory.GetTaskById(ctx, id)
+		if err != nil {
+			return err
+		}
+		concurrent.Description = "changed concurrently"
+		if err = s.Memory.UpdateTask(ctx, &concurrent); err != nil {
+			return err
+		}
+	}
+
+	return s.Memory.UpdateTask(ctx, task)
+}
+
+func TestUpdateConflict(t *testing.T) {
+	s := newTestServer(t, &racingStore{Memory: db.NewMemory(), raced: make(map[string]bool)})
+	id := s.add(t, request.AddRequest{Name: "Contract", Coordinators: []string{"a"}})
+
+	code, body := s.do(t, http.MethodPost, "/approve/a/"+id, "a", nil)
+	if code != fiber.StatusConflict {
+		t.Fatalf("status %v %v, want %v", code, body, fiber.StatusConflict)
+	}
+	var conflict response.Conflict
+	if err := json.Unmarshal([]byte(body), &conflict); err != nil {
+		t.Fatal(err)
+	}
+	if conflict.Task.Description != "changed concurrently" || conflict.Task.Status != model.NotStarted {
+		t.Errorf("conflict task = %+v, want the concurrently changed task", conflict.Task)
+	}
+
+	// Retrying against the current state succeeds.
+	if code, body = s.do(t, http.MethodPost, "/approve/a/"+id, "a", nil); code != fiber.StatusOK {
+		t.Fatalf("retry: status %v %v", code, body)
+	}
+	if !strings.Contains(body, "approved") {
+		t.Errorf("retry: %v", body)
+	}
+}
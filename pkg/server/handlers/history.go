@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/logger"
+	"github.com/richard-on/task-service/pkg/server/response"
+	"time"
+)
+
+// History
+// @Summary      History
+// @Tags         History
+// @Description  Get task history
+// @ID           task-history
+// @Produce      json
+// @Param        task_id      path      string  true  "Task ID"
+// @Success      200          {object}  response.History
+// @Failure      400,403,500  {object}  response.Error
+// @Router       /tasks/:task_id/history [get]
+func (h *TaskHandler) History(ctx *fiber.Ctx) error {
+	validateResponse, err := h.authenticate(ctx)
+	if err != nil {
+		return h.HandleAuthError(ctx, err)
+	}
+
+	taskID := ctx.Params("task_id")
+	task, err := h.Db.GetTaskById(ctx.UserContext(), taskID)
+	if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusBadRequest, err, "unable to get task")
+	} else if !task.Participant(validateResponse.Email) {
+		h.log.Debug(ErrNoAccess)
+
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{
+			Error: ErrNoAccess.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.History{
+		ID:      task.ID,
+		History: task.History,
+	})
+}
+
+// VerifyHistory
+// @Summary      Verify history
+// @Tags         History
+// @Description  Verify task history
+// @ID           task-history-verify
+// @Produce      json
+// @Param        task_id      path      string  true  "Task ID"
+// @Success      200          {object}  response.Verification
+// @Failure      400,403,500  {object}  response.Error
+// @Router       /tasks/:task_id/history/verify [get]
+func (h *TaskHandler) VerifyHistory(ctx *fiber.Ctx) error {
+	validateResponse, err := h.authenticate(ctx)
+	if err != nil {
+		return h.HandleAuthError(ctx, err)
+	}
+
+	taskID := ctx.Params("task_id")
+	task, err := h.Db.GetTaskById(ctx.UserContext(), taskID)
+	if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusBadRequest, err, "unable to get task")
+	} else if !task.Participant(validateResponse.Email) {
+		h.log.Debug(ErrNoAccess)
+
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{
+			Error: ErrNoAccess.Error(),
+		})
+	}
+
+	broken, err := task.VerifyHistory()
+	if err != nil {
+		h.log.Error(err, "unable to verify task history")
+
+		return ctx.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	verification := response.Verification{
+		ID:     task.ID,
+		Valid:  broken < 0,
+		Length: len(task.History),
+	}
+	if broken >= 0 {
+		verification.BrokenAt = &broken
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(verification)
+}
+
+// newEvent creates a history event of actor performing action within the current request.
+func (h *TaskHandler) newEvent(ctx *fiber.Ctx, actor string, action model.Action, stage int) model.Event {
+	rid, _ := ctx.Locals(logger.RequestIDKey).(string)
+
+	return model.Event{
+		Actor:     actor,
+		Action:    action,
+		Stage:     stage,
+		Time:      h.now().UTC().Truncate(time.Millisecond),
+		RequestID: rid,
+		IP:        ctx.IP(),
+	}
+}
+
+// newDecisionEvent returns an event of actor deciding for coordinator.
+func (h *TaskHandler) newDecisionEvent(ctx *fiber.Ctx, coordinator, actor string, action model.Action, stage int) model.Event {
+	event := h.newEvent(ctx, actor, action, stage)
+	if actor != coordinator {
+		event.Coordinator = coordinator
+	}
+
+	return event
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/server/response"
+)
+
+// Inbox
+// @Summary      Inbox
+// @Tags         List
+// @Description  List tasks awaiting decision
+// @ID           list-inbox
+// @Produce      json
+// @Success      200      {object}  response.ListResponse
+// @Failure      403,500,504  {object}  response.Error
+// @Router       /tasks/inbox [get]
+func (h *TaskHandler) Inbox(ctx *fiber.Ctx) error {
+	return h.listFor(ctx, h.awaiting)
+}
+
+// Participated
+// @Summary      Participated
+// @Tags         List
+// @Description  List decided tasks
+// @ID           list-participated
+// @Produce      json
+// @Success      200      {object}  response.ListResponse
+// @Failure      403,500,504  {object}  response.Error
+// @Router       /tasks/participated [get]
+func (h *TaskHandler) Participated(ctx *fiber.Ctx) error {
+	return h.listFor(ctx, h.Db.GetDecidedTasks)
+}
+
+// listFor responds with tasks returned by get for the authenticated caller.
+func (h *TaskHandler) listFor(ctx *fiber.Ctx, get func(ctx context.Context, email string) ([]model.Task, error)) error {
+	validateResponse, err := h.authenticate(ctx)
+	if err != nil {
+		return h.HandleAuthError(ctx, err)
+	}
+
+	tasks, err := get(ctx.UserContext(), validateResponse.Email)
+	if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusInternalServerError, err, "unable to get tasks")
+	}
+
+	if len(tasks) == 0 {
+		return ctx.Status(fiber.StatusOK).JSON(response.Error{Error: ErrNoTasks.Error()})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.ListResponse{Tasks: tasks})
+}
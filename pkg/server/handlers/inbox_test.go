@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/server/request"
+	"github.com/richard-on/task-service/pkg/server/response"
+)
+
+// names lists names of tasks returned by an inbox endpoint to user, sorted.
+func (s *testServer) names(t *testing.T, target, user string) []string {
+	t.Helper()
+
+	code, body := s.do(t, http.MethodGet, target, user, nil)
+	if code != fiber.StatusOK {
+		t.Fatalf("%v for %v: status %v %v", target, user, code, body)
+	}
+
+	var list response.ListResponse
+	if err := json.Unmarshal([]byte(body), &list); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, task := range list.Tasks {
+		names = append(names, task.Name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func TestInbox(t *testing.T) {
+	s := newTestServer(t, nil)
+	sequential := s.add(t, request.AddRequest{Name: "sequential", Coordinators: []string{"a", "b"}})
+	s.add(t, request.AddRequest{Name: "parallel", Coordinators: []string{"a", "c"}, Mode: model.Parallel})
+	s.add(t, request.AddRequest{Name: "other", Coordinators: []string{"b"}})
+
+	if code, body := s.do(t, http.MethodPost, "/approve/a/"+sequential, "a", nil); code != fiber.StatusOK {
+		t.Fatalf("approve: status %v %v", code, body)
+	}
+
+	tests := []struct {
+		target string
+		user   string
+		want   []string
+	}{
+		{target: "/tasks/inbox", user: "a", want: []string{"parallel"}},
+		{target: "/tasks/inbox", user: "b", want: []string{"other", "sequential"}},
+		{target: "/tasks/inbox", user: "c", want: []string{"parallel"}},
+		{target: "/tasks/inbox", user: "initiator", want: []string{}},
+		{target: "/tasks/participated", user: "a", want: []string{"sequential"}},
+		{target: "/tasks/participated", user: "b", want: []string{}},
+	}
+	for _, tt := range tests {
+		if got := s.names(t, tt.target, tt.user); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v for %v = %v, want %v", tt.target, tt.user, got, tt.want)
+		}
+	}
+
+	if code, body := s.do(t, http.MethodGet, "/tasks/inbox", "", nil); code != fiber.StatusForbidden {
+		t.Errorf("anonymous inbox: status %v %v", code, body)
+	}
+}
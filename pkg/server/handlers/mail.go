@@ -1,30 +1,138 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/richard-on/auth-service/pkg/response"
 	"github.com/richard-on/mail-service/pkg/server/request"
+	"github.com/richard-on/mail-service/pkg/templates"
+	"github.com/richard-on/task-service/config"
+	"github.com/richard-on/task-service/internal/model"
 	"github.com/valyala/fasthttp"
+	"time"
 )
 
-func SendEmail(ctx *fiber.Ctx, mailReq request.SendMail) error {
+// coordinationMail builds the email asking coordinator, or their delegate, to decide on task.
+func (h *TaskHandler) coordinationMail(from string, task model.Task, coordinator, delegate string) (request.SendMail, error) {
+	template := templates.Coordination{
+		AcceptLink: fmt.Sprintf("%v/task/v1/approve/%v/%v",
+			config.PublicURL, coordinator, task.ID.Hex()),
+		DeclineLink: fmt.Sprintf("%v/task/v1/decline/%v/%v",
+			config.PublicURL, coordinator, task.ID.Hex()),
+	}
+
+	if h.Keyring != nil {
+		var err error
+		template.AcceptLink, err = h.actionLink(task, coordinator, delegate, model.ActionApproved)
+		if err != nil {
+			return request.SendMail{}, err
+		}
+		template.DeclineLink, err = h.actionLink(task, coordinator, delegate, model.ActionDeclined)
+		if err != nil {
+			return request.SendMail{}, err
+		}
+	}
+
+	to := coordinator
+	if delegate != "" {
+		to = delegate
+	}
+
+	return request.SendMail{
+		From:     from,
+		Subject:  task.Description,
+		To:       to,
+		Type:     "coordination",
+		Template: template,
+	}, nil
+}
+
+// sendCoordinationMail asks coordinators or their delegates to decide on task. ctx is nil outside of requests.
+func (h *TaskHandler) sendCoordinationMail(ctx *fiber.Ctx, from string, task model.Task, coordinators []string) {
+	parent := context.Background()
+	if ctx != nil {
+		parent = ctx.UserContext()
+	}
+	dbCtx, cancel := context.WithTimeout(parent, config.DbTimeout)
+	defer cancel()
+
+	now := h.now()
+	for _, c := range coordinators {
+		// Coordinator is asked in person if their delegation can't be checked.
+		delegate, err := h.delegateOf(dbCtx, c, now)
+		if err != nil {
+			h.log.Error(err, "unable to get delegations")
+		}
+
+		mail, err := h.coordinationMail(from, task, c, delegate)
+		if err != nil {
+			h.log.Error(err, "unable to build coordination email")
+			continue
+		}
+
+		h.sendMail(ctx, mail)
+	}
+}
+
+// sendInfoMail sends an informational email about task to each of recipients.
+func (h *TaskHandler) sendInfoMail(ctx *fiber.Ctx, from string, task model.Task, recipients []string, body string) {
+	for _, to := range recipients {
+		h.sendMail(ctx, request.SendMail{
+			From:    from,
+			Subject: task.Description,
+			To:      to,
+			Type:    "info",
+			Template: templates.Info{
+				Body: body,
+			},
+		})
+	}
+}
+
+// Mailer sends emails through the mail service.
+type Mailer interface {
+	// Send sends mailReq authenticated with the access and refresh tokens of the sender.
+	Send(mailReq request.SendMail, accessToken, refreshToken string) error
+}
+
+// MailService is a Mailer which posts emails to the mail service at URL.
+type MailService struct {
+	URL     string
+	Timeout time.Duration
+}
+
+// sendMail sends email on behalf of the user of ctx or, if ctx is nil, on behalf of the service.
+func (h *TaskHandler) sendMail(ctx *fiber.Ctx, mailReq request.SendMail) error {
+	if ctx == nil {
+		return h.sendServiceMail(mailReq)
+	}
+
+	return h.Mailer.Send(mailReq, ctx.Cookies("accessToken"), ctx.Cookies("refreshToken"))
+}
+
+// sendServiceMail sends email on behalf of the service itself, authenticated with config.MailToken.
+func (h *TaskHandler) sendServiceMail(mailReq request.SendMail) error {
+	return h.Mailer.Send(mailReq, config.MailToken, "")
+}
 
+func (s MailService) Send(mailReq request.SendMail, accessToken, refreshToken string) error {
 	marshalled, _ := json.Marshal(mailReq)
 
 	req := fasthttp.AcquireRequest()
 	defer fasthttp.ReleaseRequest(req)
 	req.Header.SetMethod("POST")
 	req.Header.SetContentType("application/json")
-	req.SetRequestURI("http://localhost:3000/mail/v1/send")
-	req.Header.SetCookie("accessToken", ctx.Cookies("accessToken"))
-	req.Header.SetCookie("refreshToken", ctx.Cookies("refreshToken"))
+	req.SetRequestURI(s.URL)
+	req.Header.SetCookie("accessToken", accessToken)
+	req.Header.SetCookie("refreshToken", refreshToken)
 	req.SetBody(marshalled)
 
 	resp := fasthttp.AcquireResponse()
 	defer fasthttp.ReleaseResponse(resp)
 
-	err := fasthttp.Do(req, resp)
+	err := fasthttp.DoTimeout(req, resp, s.Timeout)
 	if err != nil {
 		return err
 	}
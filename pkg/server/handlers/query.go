@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/richard-on/task-service/internal/db"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/server/request"
+	"strings"
+	"time"
+)
+
+// newQuery validates listing parameters and converts them to a query of tasks initiated by initiator.
+func newQuery(initiator string, listRequest request.ListRequest) (db.Query, error) {
+	query := db.Query{
+		Initiator:   initiator,
+		Coordinator: listRequest.Coordinator,
+		Name:        listRequest.Name,
+		Limit:       listRequest.Limit,
+		Cursor:      listRequest.Cursor,
+	}
+
+	if listRequest.Status != "" {
+		for _, s := range strings.Split(listRequest.Status, ",") {
+			status := model.Status(strings.TrimSpace(s))
+			if !status.Valid() {
+				return db.Query{}, fmt.Errorf("%w: %v", model.ErrInvalidStatus, s)
+			}
+			query.Statuses = append(query.Statuses, status)
+		}
+	}
+
+	times := []struct {
+		name  string
+		value string
+		dst   *time.Time
+	}{
+		{"created_from", listRequest.CreatedFrom, &query.CreatedFrom},
+		{"created_to", listRequest.CreatedTo, &query.CreatedTo},
+		{"updated_from", listRequest.UpdatedFrom, &query.UpdatedFrom},
+		{"updated_to", listRequest.UpdatedTo, &query.UpdatedTo},
+	}
+	for _, t := range times {
+		if t.value == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, t.value)
+		if err != nil {
+			return db.Query{}, fmt.Errorf("%v: %w", t.name, err)
+		}
+		*t.dst = parsed.UTC()
+	}
+
+	query.Desc = strings.HasPrefix(listRequest.Sort, "-")
+	query.Sort = db.SortField(strings.TrimPrefix(listRequest.Sort, "-"))
+	if !query.Sort.Valid() {
+		return db.Query{}, db.ErrInvalidSort
+	}
+
+	return query, nil
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/server/request"
+	"github.com/richard-on/task-service/pkg/server/response"
+	"strings"
+)
+
+var ErrInvalidStage = errors.New("task has no such stage")
+
+// Reassign
+// @Summary      Reassign
+// @Tags         Reassign
+// @Description  Reassign stage coordinators
+// @ID           reassign
+// @Accept       json
+// @Produce      json
+// @Param        task_id  path      string                     true  "Task ID"
+// @Param        stage    path      int                        true  "Stage index"
+// @Param        input    body      request.ReassignRequest    true  "New coordinators of the stage"
+// @Success      200      {object}  response.Info
+// @Failure      400,403,409,500,504  {object}  response.Error
+// @Router       /tasks/:task_id/stages/:stage/coordinators [put]
+func (h *TaskHandler) Reassign(ctx *fiber.Ctx) error {
+	validateResponse, err := h.authenticate(ctx)
+	if err != nil {
+		return h.HandleAuthError(ctx, err)
+	}
+
+	stage, err := ctx.ParamsInt("stage")
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+	}
+
+	var reassignRequest request.ReassignRequest
+	if err = ctx.BodyParser(&reassignRequest); err != nil {
+		h.log.Debug(err, "parsing error")
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+	}
+
+	taskID := ctx.Params("task_id")
+	task, err := h.Db.GetTaskById(ctx.UserContext(), taskID)
+	if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusBadRequest, err, "unable to get task")
+	} else if validateResponse.Email != task.Initiator {
+		h.log.Debug(ErrNoAccess)
+
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{
+			Error: ErrNoAccess.Error(),
+		})
+	}
+
+	if stage < 0 || stage >= len(task.Stages) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: ErrInvalidStage.Error()})
+	}
+	if err = validateReassign(task.Stages[stage], reassignRequest); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+	}
+
+	active, previous := task.Active(), task.Stages[stage].Coordinators
+	if err = task.Reassign(stage, reassignRequest.Coordinators, reassignRequest.Quorum); errors.Is(err, model.ErrInvalidTransition) {
+		return HandleTransitionError(ctx, task, err)
+	} else if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+	}
+
+	event := h.newEvent(ctx, validateResponse.Email, model.ActionReassigned, stage)
+	event.Coordinators = reassignRequest.Coordinators
+	if err = task.Record(event); err != nil {
+		h.log.Error(err, "unable to record task history")
+		return ctx.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	err = h.Db.UpdateTask(ctx.UserContext(), &task)
+	if err != nil {
+		return h.HandleUpdateError(ctx, task.ID.Hex(), err)
+	}
+
+	asked := newlyActive(active, task)
+	h.sendCoordinationMail(ctx, task.Initiator, task, asked)
+	h.sendInfoMail(ctx, task.Initiator, task, missing(missing(reassignRequest.Coordinators, previous), asked),
+		fmt.Sprintf("ADDED AS COORDINATOR! You will be asked to decide once stage %v of the task is reached", stage+1))
+	h.sendInfoMail(ctx, task.Initiator, task, missing(previous, reassignRequest.Coordinators),
+		"REMOVED FROM COORDINATORS! Your decision on this task is no longer needed")
+
+	return ctx.Status(fiber.StatusOK).JSON(response.Info{
+		Message: fmt.Sprintf("coordinators of stage %v have been updated: awaiting decision from: %v",
+			stage+1, strings.Join(task.Active(), ", ")),
+	})
+}
+
+// validateReassign checks the requested coordinators of stage the same way newStages does for a new task.
+func validateReassign(stage model.Stage, reassignRequest request.ReassignRequest) error {
+	if len(reassignRequest.Coordinators) == 0 {
+		return ErrNoCoordinators
+	}
+
+	quorum := stage.Quorum
+	if reassignRequest.Quorum != 0 {
+		if stage.Mode != model.Quorum {
+			return ErrInvalidQuorum
+		}
+		quorum = reassignRequest.Quorum
+	}
+	if stage.Mode == model.Quorum && (quorum < 1 || quorum > len(reassignRequest.Coordinators)) {
+		return ErrInvalidQuorum
+	}
+
+	return nil
+}
+
+// missing returns emails of from which are not in other.
+func missing(from, other []string) []string {
+	var result []string
+	for _, f := range from {
+		found := false
+		for _, o := range other {
+			if o == f {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, f)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/internal/sign"
+	"github.com/richard-on/task-service/pkg/server/response"
+	"time"
+)
+
+var ErrNoDecision = errors.New("coordinator has not decided on this stage")
+
+var ErrSigningDisabled = errors.New("decision signing is not configured")
+
+// Receipt
+// @Summary      Receipt
+// @Tags         Receipt
+// @Description  Get decision receipt
+// @ID           decision-receipt
+// @Produce      json
+// @Param        task_id      path      string  true   "Task ID"
+// @Param        stage        path      int     true   "Stage index"
+// @Param        coordinator  path      string  true   "Coordinator email"
+// @Param        revision     query     int     false  "Revision number"
+// @Success      200          {object}  response.Receipt
+// @Failure      400,403,404,500  {object}  response.Error
+// @Router       /tasks/:task_id/stages/:stage/receipts/:coordinator [get]
+func (h *TaskHandler) Receipt(ctx *fiber.Ctx) error {
+	validateResponse, err := h.authenticate(ctx)
+	if err != nil {
+		return h.HandleAuthError(ctx, err)
+	}
+
+	if h.Signer == nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(response.Error{Error: ErrSigningDisabled.Error()})
+	}
+
+	stage, err := ctx.ParamsInt("stage")
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+	}
+
+	taskID := ctx.Params("task_id")
+	task, err := h.Db.GetTaskById(ctx.UserContext(), taskID)
+	if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusBadRequest, err, "unable to get task")
+	} else if !task.Participant(validateResponse.Email) && !task.Decided(validateResponse.Email) {
+		h.log.Debug(ErrNoAccess)
+
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{
+			Error: ErrNoAccess.Error(),
+		})
+	}
+
+	number, err := queryInt(ctx, "revision", task.Number())
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+	}
+	revision, err := task.FindRevision(number)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(response.Error{Error: err.Error()})
+	}
+
+	statement, decision, ok := task.RevisionStatement(number, stage, ctx.Params("coordinator"))
+	if !ok || decision.Signature == "" {
+		return ctx.Status(fiber.StatusNotFound).JSON(response.Error{Error: ErrNoDecision.Error()})
+	}
+
+	canonical, err := statement.Canonical()
+	if err != nil {
+		h.log.Error(err, "unable to build decision statement")
+
+		return ctx.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	contentHash, err := revision.ContentHash()
+	if err != nil {
+		h.log.Error(err, "unable to hash task content")
+
+		return ctx.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.Receipt{
+		Statement:      statement,
+		Canonical:      string(canonical),
+		Signature:      decision.Signature,
+		Algorithm:      sign.Algorithm,
+		PublicKey:      h.Signer.PublicKey(),
+		Valid:          h.Signer.Verify(canonical, decision.Signature),
+		ContentChanged: contentHash != statement.ContentHash,
+	})
+}
+
+// signDecision stamps coordinator's decision in stage and signs it if signing is configured.
+func (h *TaskHandler) signDecision(task *model.Task, stage int, coordinator, actor string, at time.Time) error {
+	contentHash, err := task.ContentHash()
+	if err != nil {
+		return err
+	}
+
+	_, decision, ok := task.Statement(stage, coordinator)
+	if !ok {
+		return ErrNoDecision
+	}
+	decision.Time = at
+	decision.ContentHash = contentHash
+	if actor != coordinator {
+		decision.DecidedBy = actor
+	}
+
+	if h.Signer == nil {
+		return nil
+	}
+
+	statement, _, _ := task.Statement(stage, coordinator)
+	canonical, err := statement.Canonical()
+	if err != nil {
+		return err
+	}
+	decision.Signature = h.Signer.Sign(canonical)
+
+	return nil
+}
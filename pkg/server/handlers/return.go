@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/server/response"
+	"strings"
+)
+
+var ErrReturnReasonRequired = errors.New("reason is required to return the task")
+
+// Return
+// @Summary      Return
+// @Tags         Return
+// @Description  Return task to initiator
+// @ID           return
+// @Accept       json
+// @Produce      json
+// @Param        task_id      path      string                   true  "Task ID"
+// @Param        coordinator  path      string                   true  "Coordinator"
+// @Param        input        body      request.DecisionRequest  true  "Changes the coordinator asks for"
+// @Success      200          {object}  response.Info
+// @Failure      400,403,409,500,504  {object}  response.Error
+// @Router       /return/:coordinator/:task_id [post]
+func (h *TaskHandler) Return(ctx *fiber.Ctx) error {
+	validateResponse, err := h.authenticate(ctx)
+	if err != nil {
+		return h.HandleAuthError(ctx, err)
+	}
+
+	reason, err := decisionComment(ctx, model.ActionReturned)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+	} else if reason == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: ErrReturnReasonRequired.Error()})
+	}
+
+	coordinator := ctx.Params("coordinator")
+	taskID := ctx.Params("task_id")
+	task, err := h.Db.GetTaskById(ctx.UserContext(), taskID)
+	if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusBadRequest, err, "unable to get task")
+	}
+
+	// Delegates return the task on behalf of the coordinator whose authority they hold.
+	actor := validateResponse.Email
+	if ok, err := h.actsFor(ctx.UserContext(), actor, coordinator); err != nil {
+		return h.HandleDbError(ctx, fiber.StatusInternalServerError, err, "unable to get delegations")
+	} else if !ok {
+		h.log.Debug(ErrNoAccess)
+
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{
+			Error: ErrNoAccess.Error(),
+		})
+	}
+
+	stage := task.Stage
+	event := h.newDecisionEvent(ctx, coordinator, actor, model.ActionReturned, stage)
+	event.Comment = reason
+	if err = task.Return(coordinator); err != nil {
+		return HandleTransitionError(ctx, task, err)
+	}
+	if err = task.Record(event); err != nil {
+		h.log.Error(err, "unable to record task history")
+		return ctx.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	err = h.Db.UpdateTask(ctx.UserContext(), &task)
+	if err != nil {
+		return h.HandleUpdateError(ctx, taskID, err)
+	}
+
+	h.sendInfoMail(ctx, actor, task, []string{task.Initiator},
+		fmt.Sprintf("TASK RETURNED! %v%v asks for changes at stage %v: %v",
+			actor, onBehalf(coordinator, actor), stage+1, reason))
+
+	return ctx.Status(fiber.StatusOK).JSON(response.Info{
+		Message: "you have returned this task to its initiator" + onBehalf(coordinator, actor),
+	})
+}
+
+// Resume
+// @Summary      Resume
+// @Tags         Return
+// @Description  Resume returned task
+// @ID           resume
+// @Produce      json
+// @Param        task_id  path      string  true  "Task ID"
+// @Success      200      {object}  response.Info
+// @Failure      400,403,409,500,504  {object}  response.Error
+// @Router       /tasks/:task_id/resume [post]
+func (h *TaskHandler) Resume(ctx *fiber.Ctx) error {
+	validateResponse, err := h.authenticate(ctx)
+	if err != nil {
+		return h.HandleAuthError(ctx, err)
+	}
+
+	taskID := ctx.Params("task_id")
+	task, err := h.Db.GetTaskById(ctx.UserContext(), taskID)
+	if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusBadRequest, err, "unable to get task")
+	} else if validateResponse.Email != task.Initiator {
+		h.log.Debug(ErrNoAccess)
+
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{
+			Error: ErrNoAccess.Error(),
+		})
+	}
+
+	if err = task.Resume(); err != nil {
+		return HandleTransitionError(ctx, task, err)
+	}
+	if err = task.Record(h.newEvent(ctx, validateResponse.Email, model.ActionResumed, task.Stage)); err != nil {
+		h.log.Error(err, "unable to record task history")
+		return ctx.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	err = h.Db.UpdateTask(ctx.UserContext(), &task)
+	if err != nil {
+		return h.HandleUpdateError(ctx, taskID, err)
+	}
+
+	h.sendCoordinationMail(ctx, task.Initiator, task, task.Active())
+
+	return ctx.Status(fiber.StatusOK).JSON(response.Info{
+		Message: fmt.Sprintf("successfully resumed task %v: awaiting decision from: %v",
+			taskID, strings.Join(task.Active(), ", ")),
+	})
+}
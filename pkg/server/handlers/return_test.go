@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/server/request"
+)
+
+func TestReturnResume(t *testing.T) {
+	s := newTestServer(t, nil)
+	id := s.add(t, request.AddRequest{Name: "Contract", Coordinators: []string{"a", "b"}})
+	reason := &request.DecisionRequest{Comment: "wrong amount"}
+
+	steps := []struct {
+		name   string
+		target string
+		user   string
+		body   interface{}
+		want   int
+	}{
+		{name: "resume a task which is not returned", target: "/tasks/" + id + "/resume", user: "initiator",
+			want: fiber.StatusForbidden},
+		{name: "approve", target: "/approve/a/" + id, user: "a", want: fiber.StatusOK},
+		{name: "return without a reason", target: "/return/b/" + id, user: "b", want: fiber.StatusBadRequest},
+		{name: "return out of turn", target: "/return/a/" + id, user: "a", body: reason, want: fiber.StatusForbidden},
+		{name: "return on behalf of another coordinator", target: "/return/b/" + id, user: "a", body: reason,
+			want: fiber.StatusForbidden},
+		{name: "return", target: "/return/b/" + id, user: "b", body: reason, want: fiber.StatusOK},
+		{name: "approve a returned task", target: "/approve/b/" + id, user: "b", want: fiber.StatusForbidden},
+		{name: "resume by a coordinator", target: "/tasks/" + id + "/resume", user: "b", want: fiber.StatusForbidden},
+		{name: "resume", target: "/tasks/" + id + "/resume", user: "initiator", want: fiber.StatusOK},
+		{name: "resume again", target: "/tasks/" + id + "/resume", user: "initiator", want: fiber.StatusForbidden},
+		{name: "approve after resuming", target: "/approve/b/" + id, user: "b", want: fiber.StatusOK},
+	}
+	for _, step := range steps {
+		if code, body := s.do(t, http.MethodPost, step.target, step.user, step.body); code != step.want {
+			t.Fatalf("%v: status %v %v, want %v", step.name, code, body, step.want)
+		}
+	}
+
+	task := s.task(t, id)
+	var actions []model.Action
+	for _, e := range task.History {
+		actions = append(actions, e.Action)
+	}
+	want := []model.Action{model.ActionCreated, model.ActionApproved, model.ActionReturned, model.ActionResumed,
+		model.ActionApproved}
+	if task.Status != model.Approved || !reflect.DeepEqual(actions, want) {
+		t.Fatalf("status %v, history %v, want approved after %v", task.Status, actions, want)
+	}
+	if comment := task.History[2].Comment; comment != reason.Comment {
+		t.Errorf("return reason %q, want %q", comment, reason.Comment)
+	}
+
+	// The initiator learns what to change and b is asked again once the task is resumed.
+	if to := s.mailer.recipients("info"); len(to) == 0 || to[0] != "initiator" {
+		t.Errorf("info emails to %v, want the initiator told about the return", to)
+	}
+	if to := s.mailer.recipients("coordination"); !reflect.DeepEqual(to, []string{"a", "b", "b"}) {
+		t.Errorf("coordination emails to %v, want [a b b]", to)
+	}
+}
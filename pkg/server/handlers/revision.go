@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/server/request"
+	"github.com/richard-on/task-service/pkg/server/response"
+	"strconv"
+	"time"
+)
+
+// Resubmit
+// @Summary      Resubmit
+// @Tags         Revision
+// @Description  Resubmit declined task
+// @ID           resubmit
+// @Accept       json
+// @Produce      json
+// @Param        task_id  path      string              true  "Task ID"
+// @Param        input    body      request.AddRequest  true  "Changes of the task"
+// @Success      200      {object}  response.AddResponse
+// @Failure      400,403,409,500,504  {object}  response.Error
+// @Router       /tasks/:task_id/resubmit [post]
+func (h *TaskHandler) Resubmit(ctx *fiber.Ctx) error {
+	validateResponse, err := h.authenticate(ctx)
+	if err != nil {
+		return h.HandleAuthError(ctx, err)
+	}
+
+	var resubmitRequest request.AddRequest
+	if err = ctx.BodyParser(&resubmitRequest); err != nil {
+		h.log.Debug(err, "parsing error")
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+	}
+
+	taskID := ctx.Params("task_id")
+	task, err := h.Db.GetTaskById(ctx.UserContext(), taskID)
+	if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusBadRequest, err, "unable to get task")
+	} else if validateResponse.Email != task.Initiator {
+		h.log.Debug(ErrNoAccess)
+
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{
+			Error: ErrNoAccess.Error(),
+		})
+	}
+
+	name, description := task.Name, task.Description
+	if resubmitRequest.Name != "" {
+		name = resubmitRequest.Name
+	}
+	if resubmitRequest.Description != "" {
+		description = resubmitRequest.Description
+	}
+
+	stages := restartStages(task.Stages)
+	if len(resubmitRequest.Stages) > 0 || len(resubmitRequest.Coordinators) > 0 {
+		if stages, _, err = newStages(resubmitRequest); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+		}
+	}
+
+	dueAt := task.DueAt
+	if resubmitRequest.DueAt != nil {
+		due := resubmitRequest.DueAt.UTC().Truncate(time.Millisecond)
+		dueAt = &due
+	}
+	if dueAt != nil && !dueAt.After(h.now()) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: ErrDueInPast.Error()})
+	}
+
+	if err = task.Resubmit(name, description, stages, dueAt); err != nil {
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{Error: err.Error()})
+	}
+	if err = task.Record(h.newEvent(ctx, validateResponse.Email, model.ActionResubmitted, 0)); err != nil {
+		h.log.Error(err, "unable to record task history")
+		return ctx.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	err = h.Db.UpdateTask(ctx.UserContext(), &task)
+	if err != nil {
+		return h.HandleUpdateError(ctx, taskID, err)
+	}
+
+	h.sendCoordinationMail(ctx, validateResponse.Email, task, task.Active())
+
+	return ctx.Status(fiber.StatusOK).JSON(response.AddResponse{
+		ID:           task.ID,
+		Initiator:    task.Initiator,
+		Name:         task.Name,
+		Description:  task.Description,
+		Coordinators: task.Coordinators,
+		Stages:       task.Stages,
+		Status:       task.Status,
+		DueAt:        task.DueAt,
+		Revision:     task.Number(),
+	})
+}
+
+// Revisions
+// @Summary      Revisions
+// @Tags         Revision
+// @Description  List task revisions
+// @ID           revisions
+// @Produce      json
+// @Param        task_id  path      string  true  "Task ID"
+// @Success      200      {object}  response.Revisions
+// @Failure      400,403,500,504  {object}  response.Error
+// @Router       /tasks/:task_id/revisions [get]
+func (h *TaskHandler) Revisions(ctx *fiber.Ctx) error {
+	validateResponse, err := h.authenticate(ctx)
+	if err != nil {
+		return h.HandleAuthError(ctx, err)
+	}
+
+	taskID := ctx.Params("task_id")
+	task, err := h.Db.GetTaskById(ctx.UserContext(), taskID)
+	if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusBadRequest, err, "unable to get task")
+	} else if !task.Participant(validateResponse.Email) && !task.Decided(validateResponse.Email) {
+		h.log.Debug(ErrNoAccess)
+
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{
+			Error: ErrNoAccess.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.Revisions{
+		ID:        task.ID,
+		Revision:  task.Number(),
+		Revisions: task.AllRevisions(),
+	})
+}
+
+// RevisionDiff
+// @Summary      Revision diff
+// @Tags         Revision
+// @Description  Diff task revisions
+// @ID           revision-diff
+// @Produce      json
+// @Param        task_id  path      string  true   "Task ID"
+// @Param        from     query     int     false  "Earlier revision number"
+// @Param        to       query     int     false  "Later revision number"
+// @Success      200      {object}  response.RevisionDiff
+// @Failure      400,403,404,500,504  {object}  response.Error
+// @Router       /tasks/:task_id/revisions/diff [get]
+func (h *TaskHandler) RevisionDiff(ctx *fiber.Ctx) error {
+	validateResponse, err := h.authenticate(ctx)
+	if err != nil {
+		return h.HandleAuthError(ctx, err)
+	}
+
+	taskID := ctx.Params("task_id")
+	task, err := h.Db.GetTaskById(ctx.UserContext(), taskID)
+	if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusBadRequest, err, "unable to get task")
+	} else if !task.Participant(validateResponse.Email) && !task.Decided(validateResponse.Email) {
+		h.log.Debug(ErrNoAccess)
+
+		return ctx.Status(fiber.StatusForbidden).JSON(response.Error{
+			Error: ErrNoAccess.Error(),
+		})
+	}
+
+	to, err := queryInt(ctx, "to", task.Number())
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+	}
+	from, err := queryInt(ctx, "from", to-1)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+	}
+
+	fromRevision, err := task.FindRevision(from)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(response.Error{Error: err.Error()})
+	}
+	toRevision, err := task.FindRevision(to)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(response.Error{Error: err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.RevisionDiff{
+		ID:      task.ID,
+		From:    from,
+		To:      to,
+		Changes: model.Diff(fromRevision, toRevision),
+	})
+}
+
+// queryInt returns the integer query parameter key or def if it is not set.
+func queryInt(ctx *fiber.Ctx, key string, def int) (int, error) {
+	if ctx.Query(key) == "" {
+		return def, nil
+	}
+
+	return strconv.Atoi(ctx.Query(key))
+}
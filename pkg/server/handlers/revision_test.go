@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/server/request"
+	"github.com/richard-on/task-service/pkg/server/response"
+)
+
+func TestResubmit(t *testing.T) {
+	s := newTestServer(t, nil)
+	id := s.add(t, request.AddRequest{Name: "Contract", Description: "draft", Coordinators: []string{"a"}})
+
+	steps := []struct {
+		name string
+		path string
+		user string
+		body interface{}
+		want int
+	}{
+		{name: "resubmit of a pending task", path: "/tasks/" + id + "/resubmit", user: "initiator", body: request.AddRequest{}, want: fiber.StatusForbidden},
+		{name: "decline", path: "/decline/a/" + id, user: "a", want: fiber.StatusOK},
+		{name: "resubmit by a coordinator", path: "/tasks/" + id + "/resubmit", user: "a", body: request.AddRequest{}, want: fiber.StatusForbidden},
+		{
+			name: "resubmit",
+			path: "/tasks/" + id + "/resubmit",
+			user: "initiator",
+			body: request.AddRequest{Description: "final", Coordinators: []string{"a", "b"}},
+			want: fiber.StatusOK,
+		},
+		{name: "approve of the new revision", path: "/approve/a/" + id, user: "a", want: fiber.StatusOK},
+	}
+	for _, step := range steps {
+		if code, body := s.do(t, http.MethodPost, step.path, step.user, step.body); code != step.want {
+			t.Fatalf("%v: status %v %v, want %v", step.name, code, body, step.want)
+		}
+	}
+
+	task := s.task(t, id)
+	if task.Number() != 2 || task.Status != model.InProgress {
+		t.Errorf("revision %v in status %v, want revision 2 %v", task.Number(), task.Status, model.InProgress)
+	}
+	if task.Name != "Contract" || task.Description != "final" {
+		t.Errorf("content %q, %q, want the name kept and the description changed", task.Name, task.Description)
+	}
+
+	code, body := s.do(t, http.MethodGet, "/tasks/"+id+"/revisions", "b", nil)
+	if code != fiber.StatusOK {
+		t.Fatalf("revisions: status %v %v", code, body)
+	}
+	var revisions response.Revisions
+	if err := json.Unmarshal([]byte(body), &revisions); err != nil {
+		t.Fatal(err)
+	}
+	if revisions.Revision != 2 || len(revisions.Revisions) != 2 || revisions.Revisions[0].Status != model.Declined {
+		t.Errorf("revisions %+v, want the declined one and the current one", revisions)
+	}
+
+	code, body = s.do(t, http.MethodGet, "/tasks/"+id+"/revisions/diff", "initiator", nil)
+	if code != fiber.StatusOK {
+		t.Fatalf("diff: status %v %v", code, body)
+	}
+	var diff response.RevisionDiff
+	if err := json.Unmarshal([]byte(body), &diff); err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Changes) == 0 || diff.Changes[0] != (model.Change{Field: "description", Old: "draft", New: "final"}) {
+		t.Errorf("changes %+v, want the description change first", diff.Changes)
+	}
+
+	if code, body = s.do(t, http.MethodGet, "/tasks/"+id+"/revisions/diff?from=3", "initiator", nil); code != fiber.StatusNotFound {
+		t.Errorf("diff from an unknown revision: status %v %v", code, body)
+	}
+}
+
+func TestActionRevision(t *testing.T) {
+	s := newTestServer(t, nil)
+	id := s.add(t, request.AddRequest{Name: "Contract", Coordinators: []string{"a"}})
+
+	approve := s.actionToken(t, id, "a", "", model.ActionApproved)
+	if code, body := s.do(t, http.MethodPost, "/decline/a/"+id, "a", nil); code != fiber.StatusOK {
+		t.Fatalf("decline: status %v %v", code, body)
+	}
+	if code, body := s.do(t, http.MethodPost, "/tasks/"+id+"/resubmit", "initiator", request.AddRequest{}); code != fiber.StatusOK {
+		t.Fatalf("resubmit: status %v %v", code, body)
+	}
+
+	if code, body := s.do(t, http.MethodPost, "/action?token="+url.QueryEscape(approve), "", nil); code != fiber.StatusForbidden {
+		t.Errorf("token of the previous revision: status %v %v", code, body)
+	}
+	if code, body := s.do(t, http.MethodPost, "/action?token="+url.QueryEscape(s.actionToken(t, id, "a", "", model.ActionApproved)), "", nil); code != fiber.StatusOK {
+		t.Errorf("token of the current revision: status %v %v", code, body)
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/internal/db"
+	"github.com/richard-on/task-service/pkg/server/response"
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrNoSearchTerms = errors.New("search query must contain at least one word")
+
+// fragmentContext is the number of bytes shown around the first match in a highlighted description.
+const fragmentContext = 80
+
+// Search
+// @Summary      Search
+// @Tags         List
+// @Description  Search tasks
+// @ID           search-tasks
+// @Produce      json
+// @Param        q      query     string  true   "Search words"
+// @Param        limit  query     int     false  "Maximum number of results"
+// @Success      200    {object}  response.Search
+// @Failure      400,403,500,504  {object}  response.Error
+// @Router       /tasks/search [get]
+func (h *TaskHandler) Search(ctx *fiber.Ctx) error {
+	validateResponse, err := h.authenticate(ctx)
+	if err != nil {
+		return h.HandleAuthError(ctx, err)
+	}
+
+	text := ctx.Query("q")
+	terms := db.SearchTerms(text)
+	if len(terms) == 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: ErrNoSearchTerms.Error()})
+	}
+
+	limit := 0
+	if ctx.Query("limit") != "" {
+		if limit, err = strconv.Atoi(ctx.Query("limit")); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(response.Error{Error: err.Error()})
+		}
+	}
+
+	found, err := h.Db.SearchTasks(ctx.UserContext(), validateResponse.Email, text, limit)
+	if err != nil {
+		return h.HandleDbError(ctx, fiber.StatusInternalServerError, err, "unable to search tasks")
+	}
+
+	results := make([]response.SearchResult, 0, len(found))
+	for _, f := range found {
+		highlights := make(map[string]string)
+		if name, ok := highlight(f.Task.Name, terms, 0); ok {
+			highlights["name"] = name
+		}
+		if description, ok := highlight(f.Task.Description, terms, fragmentContext); ok {
+			highlights["description"] = description
+		}
+
+		results = append(results, response.SearchResult{Task: f.Task, Score: f.Score, Highlights: highlights})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.Search{Results: results})
+}
+
+// highlight HTML-escapes text and wraps matching words in <em>, cut to context bytes around the first match.
+func highlight(text string, terms []string, context int) (string, bool) {
+	var matched [][2]int
+	for _, w := range words(text) {
+		if matches(strings.ToLower(text[w[0]:w[1]]), terms) {
+			matched = append(matched, w)
+		}
+	}
+	if len(matched) == 0 {
+		return "", false
+	}
+
+	from, to := 0, len(text)
+	if context > 0 {
+		from, to = clamp(text, matched[0][0]-context), clamp(text, matched[0][1]+context)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+
+	pos := from
+	for _, w := range matched {
+		if w[0] < from || w[1] > to {
+			continue
+		}
+
+		b.WriteString(html.EscapeString(text[pos:w[0]]))
+		b.WriteString("<em>" + html.EscapeString(text[w[0]:w[1]]) + "</em>")
+		pos = w[1]
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+
+	if to < len(text) {
+		b.WriteString("…")
+	}
+
+	return b.String(), true
+}
+
+// words returns byte offsets of words in text, split the same way as db.SearchTerms.
+func words(text string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsNumber(r)
+		if inWord && start < 0 {
+			start = i
+		} else if !inWord && start >= 0 {
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+
+	return spans
+}
+
+func matches(word string, terms []string) bool {
+	for _, term := range terms {
+		if strings.Contains(word, term) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clamp moves byte offset i into text and back to the start of a rune.
+func clamp(text string, i int) int {
+	if i <= 0 {
+		return 0
+	} else if i >= len(text) {
+		return len(text)
+	}
+
+	for i > 0 && !utf8.RuneStart(text[i]) {
+		i--
+	}
+
+	return i
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		terms   []string
+		context int
+		want    string
+		found   bool
+	}{
+		{
+			name:  "no match",
+			text:  "Supply contract",
+			terms: []string{"lease"},
+		},
+		{
+			name:  "whole text",
+			text:  "Supply contracts for 2023",
+			terms: []string{"contract", "2023"},
+			want:  "Supply <em>contracts</em> for <em>2023</em>",
+			found: true,
+		},
+		{
+			name:  "escaped",
+			text:  "<b>Contract</b> & terms",
+			terms: []string{"contract"},
+			want:  "&lt;b&gt;<em>Contract</em>&lt;/b&gt; &amp; terms",
+			found: true,
+		},
+		{
+			name:    "fragment",
+			text:    "The budget for next year includes the new contract with the supplier and its terms",
+			terms:   []string{"contract"},
+			context: 8,
+			want:    "…the new <em>contract</em> with th…",
+			found:   true,
+		},
+		{
+			name:    "fragment of multibyte text",
+			text:    "Бюджет на год: договор поставки",
+			terms:   []string{"договор"},
+			context: 3,
+			want:    "…д: <em>договор</em> п…",
+			found:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := highlight(tt.text, tt.terms, tt.context)
+			if got != tt.want || found != tt.found {
+				t.Errorf("highlight() = %q, %v, want %q, %v", got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/server/request"
+)
+
+// newStages validates the requested stages and returns them along with distinct coordinators.
+func newStages(addRequest request.AddRequest) ([]model.Stage, []string, error) {
+	stageRequests := addRequest.Stages
+	if len(stageRequests) == 0 {
+		stageRequests = []request.StageRequest{{
+			Coordinators: addRequest.Coordinators,
+			Mode:         addRequest.Mode,
+			Quorum:       addRequest.Quorum,
+			SLA:          addRequest.SLA,
+			Escalation:   addRequest.Escalation,
+			EscalateTo:   addRequest.EscalateTo,
+		}}
+	}
+
+	var coordinators []string
+	seen := make(map[string]bool)
+	stages := make([]model.Stage, 0, len(stageRequests))
+	for _, s := range stageRequests {
+		if len(s.Coordinators) == 0 {
+			return nil, nil, ErrNoCoordinators
+		}
+		if !s.Mode.Valid() {
+			return nil, nil, ErrInvalidMode
+		}
+		if s.Mode == "" {
+			s.Mode = model.Sequential
+		}
+		if s.Mode == model.Quorum && (s.Quorum < 1 || s.Quorum > len(s.Coordinators)) {
+			return nil, nil, ErrInvalidQuorum
+		}
+		if s.SLA < 0 || !s.Escalation.Valid() {
+			return nil, nil, ErrInvalidEscalation
+		}
+		if s.SLA > 0 && s.Escalation != model.EscalateSkip && s.EscalateTo == "" {
+			return nil, nil, ErrNoEscalationContact
+		}
+
+		// A coordinator decides once per stage, so listing them twice would leave the stage waiting forever.
+		inStage := make(map[string]bool, len(s.Coordinators))
+		for _, c := range s.Coordinators {
+			if inStage[c] {
+				return nil, nil, model.ErrDuplicateCoordinator
+			}
+			inStage[c] = true
+
+			if !seen[c] {
+				seen[c] = true
+				coordinators = append(coordinators, c)
+			}
+		}
+
+		stages = append(stages, model.Stage{
+			Name:         s.Name,
+			Coordinators: s.Coordinators,
+			Mode:         s.Mode,
+			Quorum:       s.Quorum,
+			SLA:          s.SLA,
+			Escalation:   s.Escalation,
+			EscalateTo:   s.EscalateTo,
+		})
+	}
+
+	return stages, coordinators, nil
+}
+
+// restartStages returns stages with the same configuration and no decisions, for a new revision of a task.
+func restartStages(stages []model.Stage) []model.Stage {
+	restarted := make([]model.Stage, len(stages))
+	for i, s := range stages {
+		s.Coordinators = append([]string(nil), s.Coordinators...)
+		s.Decisions = nil
+		s.Next = 0
+		restarted[i] = s
+	}
+
+	return restarted
+}
+
+// newlyActive returns coordinators of task who were not active before.
+func newlyActive(before []string, task model.Task) []string {
+	var active []string
+	for _, c := range task.Active() {
+		found := false
+		for _, b := range before {
+			if b == c {
+				found = true
+				break
+			}
+		}
+		if !found {
+			active = append(active, c)
+		}
+	}
+
+	return active
+}
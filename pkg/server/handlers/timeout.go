@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"time"
+)
+
+// Timeout is a middleware which limits storage calls of a request to timeout.
+// Fasthttp doesn't report client disconnects, so requests run until they finish or time out.
+func Timeout(timeout time.Duration) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		userCtx, cancel := context.WithTimeout(ctx.UserContext(), timeout)
+		defer cancel()
+
+		ctx.SetUserContext(userCtx)
+
+		return ctx.Next()
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/internal/db"
+	"github.com/richard-on/task-service/internal/model"
+)
+
+// slowStore doesn't answer until the context of the call is done.
+type slowStore struct {
+	*db.Memory
+}
+
+func (slowStore) GetTaskById(ctx context.Context, _ string) (model.Task, error) {
+	<-ctx.Done()
+
+	return model.Task{}, ctx.Err()
+}
+
+func TestTimeout(t *testing.T) {
+	s := newTestServer(t, slowStore{Memory: db.NewMemory()})
+
+	app := fiber.New()
+	app.Use(Timeout(10 * time.Millisecond))
+	app.Post("/approve/:coordinator/:task_id", s.handler.Approve)
+	s.app = app
+
+	code, body := s.do(t, http.MethodPost, "/approve/a/"+model.Task{}.ID.Hex(), "a", nil)
+	if code != fiber.StatusGatewayTimeout {
+		t.Errorf("status %v %v, want %v", code, body, fiber.StatusGatewayTimeout)
+	}
+}
+
+func TestHandleDbError(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		want   int
+	}{
+		{name: "timeout", err: context.DeadlineExceeded, status: fiber.StatusInternalServerError, want: fiber.StatusGatewayTimeout},
+		{name: "not found", err: db.ErrNotFound, status: fiber.StatusBadRequest, want: fiber.StatusBadRequest},
+		{name: "internal", err: errors.New("connection refused"), status: fiber.StatusInternalServerError,
+			want: fiber.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(ctx *fiber.Ctx) error {
+				return s.handler.HandleDbError(ctx, tt.status, tt.err, "unable to get task")
+			})
+			s.app = app
+
+			if code, body := s.do(t, http.MethodGet, "/", "", nil); code != tt.want {
+				t.Errorf("status %v %v, want %v", code, body, tt.want)
+			}
+		})
+	}
+}
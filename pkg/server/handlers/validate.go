@@ -3,10 +3,19 @@ package handlers
 import (
 	"encoding/json"
 	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/auth-service/pkg/authService"
 	"github.com/richard-on/auth-service/pkg/response"
 	"github.com/valyala/fasthttp"
 )
 
+// authenticate checks the access and refresh tokens of the request with the auth service.
+func (h *TaskHandler) authenticate(ctx *fiber.Ctx) (*authService.ValidateResponse, error) {
+	return h.AuthService.Validate(ctx.Context(), &authService.ValidateRequest{
+		AccessToken:  ctx.Cookies("accessToken"),
+		RefreshToken: ctx.Cookies("refreshToken"),
+	})
+}
+
 func Validate(ctx *fiber.Ctx) (response.ValidateSuccess, error) {
 	req := fasthttp.AcquireRequest()
 	defer fasthttp.ReleaseRequest(req)
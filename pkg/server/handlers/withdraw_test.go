@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/config"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/server/request"
+)
+
+func TestWithdraw(t *testing.T) {
+	s := newTestServer(t, nil)
+	id := s.add(t, request.AddRequest{Name: "Contract", Stages: []request.StageRequest{
+		{Coordinators: []string{"a", "b"}},
+		{Coordinators: []string{"c"}},
+	}})
+
+	steps := []struct {
+		name   string
+		method string
+		target string
+		user   string
+		want   int
+	}{
+		{name: "by a coordinator", method: http.MethodPost, target: "/tasks/" + id + "/withdraw", user: "a",
+			want: fiber.StatusForbidden},
+		{name: "approve", method: http.MethodPost, target: "/approve/a/" + id, user: "a", want: fiber.StatusOK},
+		{name: "withdraw", method: http.MethodPost, target: "/tasks/" + id + "/withdraw", user: "initiator",
+			want: fiber.StatusOK},
+		{name: "withdraw again", method: http.MethodPost, target: "/tasks/" + id + "/withdraw", user: "initiator",
+			want: fiber.StatusForbidden},
+		{name: "approve withdrawn task", method: http.MethodPost, target: "/approve/b/" + id, user: "b",
+			want: fiber.StatusForbidden},
+	}
+	for _, step := range steps {
+		if code, body := s.do(t, step.method, step.target, step.user, nil); code != step.want {
+			t.Fatalf("%v: status %v %v, want %v", step.name, code, body, step.want)
+		}
+	}
+
+	task := s.task(t, id)
+	if task.Status != model.Withdrawn || len(task.History) != 3 || task.History[2].Action != model.ActionWithdrawn {
+		t.Errorf("status %v, history %+v, want withdrawn with its history kept", task.Status, task.History)
+	}
+	// Coordinators of later stages have never been asked, so they are not told either.
+	if to := s.mailer.recipients("info"); !reflect.DeepEqual(to, []string{"a", "b"}) {
+		t.Errorf("withdrawal emails to %v, want [a b]", to)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	s := newTestServer(t, nil)
+	admins := config.Admins
+	t.Cleanup(func() { config.Admins = admins })
+	config.Admins = []string{"admin"}
+
+	id := s.add(t, request.AddRequest{Name: "Contract", Coordinators: []string{"a"}})
+
+	steps := []struct {
+		name   string
+		target string
+		user   string
+		want   int
+	}{
+		{name: "anonymous", target: "/admin/tasks/" + id, want: fiber.StatusForbidden},
+		{name: "initiator", target: "/admin/tasks/" + id, user: "initiator", want: fiber.StatusForbidden},
+		{name: "invalid ID", target: "/admin/tasks/1", user: "admin", want: fiber.StatusBadRequest},
+		{name: "admin", target: "/admin/tasks/" + id, user: "admin", want: fiber.StatusOK},
+		{name: "purged task", target: "/admin/tasks/" + id, user: "admin", want: fiber.StatusNotFound},
+	}
+	for _, step := range steps {
+		if code, body := s.do(t, http.MethodDelete, step.target, step.user, nil); code != step.want {
+			t.Fatalf("%v: status %v %v, want %v", step.name, code, body, step.want)
+		}
+	}
+}
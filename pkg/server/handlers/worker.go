@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/richard-on/mail-service/pkg/server/request"
+	"github.com/richard-on/mail-service/pkg/templates"
+	"github.com/richard-on/task-service/config"
+	"github.com/richard-on/task-service/internal/db"
+	"github.com/richard-on/task-service/internal/model"
+	"strings"
+	"time"
+)
+
+// RunWorker performs time based changes of tasks every interval until ctx is done.
+// A non-positive interval disables the worker.
+func (h *TaskHandler) RunWorker(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	h.runWorker(ctx, ticker.C)
+}
+
+// runWorker runs the worker at once and then on every tick until ctx is done.
+func (h *TaskHandler) runWorker(ctx context.Context, tick <-chan time.Time) {
+	for {
+		h.expireOverdue(ctx)
+		h.escalateBreached(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		}
+	}
+}
+
+// expireOverdue moves tasks which are past their due date to Expired and notifies their initiators.
+func (h *TaskHandler) expireOverdue(ctx context.Context) {
+	now := h.now().UTC().Truncate(time.Millisecond)
+
+	dbCtx, cancel := context.WithTimeout(ctx, config.DbTimeout)
+	tasks, err := h.Db.GetOverdueTasks(dbCtx, now)
+	cancel()
+	if err != nil {
+		h.log.Error(err, "unable to get overdue tasks")
+		return
+	}
+
+	for _, task := range tasks {
+		if err = task.Expire(now); err != nil {
+			h.log.Error(err, "unable to expire task")
+			continue
+		}
+
+		dbCtx, cancel = context.WithTimeout(ctx, config.DbTimeout)
+		err = h.Db.UpdateTask(dbCtx, &task)
+		cancel()
+		if errors.Is(err, db.ErrConflict) {
+			continue
+		} else if err != nil {
+			h.log.Error(err, "unable to expire task")
+			continue
+		}
+
+		err = h.sendServiceMail(request.SendMail{
+			From:    task.Initiator,
+			Subject: task.Description,
+			To:      task.Initiator,
+			Type:    "info",
+			Template: templates.Info{
+				Body: fmt.Sprintf("TASK EXPIRED! It was due at %v", task.DueAt.Format(time.RFC1123)),
+			},
+		})
+		if err != nil {
+			h.log.Error(err, "unable to notify initiator of expired task")
+		}
+	}
+}
+
+// escalateBreached applies escalation policies to tasks whose coordinators are past the SLA of the current stage.
+func (h *TaskHandler) escalateBreached(ctx context.Context) {
+	now := h.now().UTC().Truncate(time.Millisecond)
+
+	dbCtx, cancel := context.WithTimeout(ctx, config.DbTimeout)
+	tasks, err := h.Db.GetBreachedTasks(dbCtx, now)
+	cancel()
+	if err != nil {
+		h.log.Error(err, "unable to get tasks past their SLA")
+		return
+	}
+
+	for _, task := range tasks {
+		active, stalled, contact := task.Active(), task.Stalled(now), task.Current().EscalateTo
+		since := now
+		for _, c := range stalled {
+			if started := task.ActiveSince(c); started.Before(since) {
+				since = started
+			}
+		}
+
+		policy, err := task.Escalate(now)
+		if err != nil {
+			h.log.Error(err, "unable to escalate task")
+			continue
+		}
+
+		dbCtx, cancel = context.WithTimeout(ctx, config.DbTimeout)
+		err = h.Db.UpdateTask(dbCtx, &task)
+		cancel()
+		if errors.Is(err, db.ErrConflict) {
+			continue
+		} else if err != nil {
+			h.log.Error(err, "unable to escalate task")
+			continue
+		}
+
+		switch {
+		case policy == model.EscalateNotify:
+			err = h.sendServiceMail(request.SendMail{
+				From:    task.Initiator,
+				Subject: task.Description,
+				To:      contact,
+				Type:    "info",
+				Template: templates.Info{
+					Body: fmt.Sprintf("TASK ESCALATED! Awaiting decision from %v since %v",
+						strings.Join(stalled, ", "), since.Format(time.RFC1123)),
+				},
+			})
+			if err != nil {
+				h.log.Error(err, "unable to notify escalation contact")
+			}
+		case task.Status == model.Approved:
+			h.sendApprovedMail(nil, task.Initiator, task)
+		case task.Status == model.Declined:
+			h.sendInfoMail(nil, task.Initiator, task, []string{task.Initiator},
+				fmt.Sprintf("TASK DECLINED! Stage %v can no longer be approved after skipping %v",
+					task.Stage+1, strings.Join(stalled, ", ")))
+		default:
+			h.sendCoordinationMail(nil, task.Initiator, task, newlyActive(active, task))
+		}
+	}
+}
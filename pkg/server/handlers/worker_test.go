@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/richard-on/task-service/internal/model"
+	"github.com/richard-on/task-service/pkg/server/request"
+)
+
+func TestExpireOverdue(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	tasks := []struct {
+		name   string
+		status model.Status
+		dueAt  *time.Time
+		want   model.Status
+	}{
+		{name: "overdue", status: model.NotStarted, dueAt: &past, want: model.Expired},
+		{name: "overdue in progress", status: model.InProgress, dueAt: &past, want: model.Expired},
+		{name: "due later", status: model.NotStarted, dueAt: &future, want: model.NotStarted},
+		{name: "no due date", status: model.InProgress, want: model.InProgress},
+		{name: "finished before due date", status: model.Approved, dueAt: &past, want: model.Approved},
+	}
+
+	ids := make([]string, len(tasks))
+	for i, tt := range tasks {
+		task, err := s.store.AddTask(ctx, model.Task{
+			Name:      tt.name,
+			Initiator: tt.name,
+			Status:    tt.status,
+			DueAt:     tt.dueAt,
+			Stages:    []model.Stage{{Coordinators: []string{"a"}}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = task.ID.Hex()
+	}
+
+	// Running the worker again changes nothing.
+	s.handler.expireOverdue(ctx)
+	s.handler.expireOverdue(ctx)
+
+	for i, tt := range tasks {
+		task := s.task(t, ids[i])
+		if task.Status != tt.want {
+			t.Errorf("%v: status %v, want %v", tt.name, task.Status, tt.want)
+		}
+		if expired := tt.want == model.Expired; expired != (len(task.History) == 1) {
+			t.Errorf("%v: history %+v", tt.name, task.History)
+		} else if expired && (task.History[0].Action != model.ActionExpired || task.History[0].Actor != model.SystemActor) {
+			t.Errorf("%v: event %+v, want expiry by the system", tt.name, task.History[0])
+		}
+	}
+
+	to := s.mailer.recipients("info")
+	sort.Strings(to)
+	if !reflect.DeepEqual(to, []string{"overdue", "overdue in progress"}) {
+		t.Errorf("expiry emails to %v, want the initiators of overdue tasks", to)
+	}
+}
+
+func TestRunWorker(t *testing.T) {
+	s := newTestServer(t, nil)
+	past := s.clock.Now().Add(-time.Hour)
+	add := func() string {
+		task, err := s.store.AddTask(context.Background(), model.Task{
+			Name:      "Contract",
+			Initiator: "initiator",
+			Status:    model.NotStarted,
+			DueAt:     &past,
+			Stages:    []model.Stage{{Coordinators: []string{"a"}}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return task.ID.Hex()
+	}
+
+	// A disabled worker returns without running, even though ctx is never done.
+	id := add()
+	for _, interval := range []time.Duration{0, -time.Second} {
+		s.handler.RunWorker(context.Background(), interval)
+	}
+	if status := s.task(t, id).Status; status != model.NotStarted {
+		t.Fatalf("disabled worker changed status to %v", status)
+	}
+
+	// The worker runs at once and then on every tick. Each tick is received only after the previous run.
+	ctx, cancel := context.WithCancel(context.Background())
+	tick := make(chan time.Time)
+	done := make(chan struct{})
+	go func() {
+		s.handler.runWorker(ctx, tick)
+		close(done)
+	}()
+
+	tick <- s.clock.Now()
+	if status := s.task(t, id).Status; status != model.Expired {
+		t.Errorf("status after the first run %v, want %v", status, model.Expired)
+	}
+
+	id = add()
+	tick <- s.clock.Now()
+	tick <- s.clock.Now()
+	cancel()
+	<-done
+	if status := s.task(t, id).Status; status != model.Expired {
+		t.Errorf("status after a tick %v, want %v", status, model.Expired)
+	}
+}
+
+func TestEscalateSkipDeclines(t *testing.T) {
+	s := newTestServer(t, nil)
+	id := s.add(t, request.AddRequest{
+		Name:         "Contract",
+		Coordinators: []string{"a", "b", "c"},
+		Mode:         model.Quorum,
+		Quorum:       2,
+		SLA:          model.Duration(time.Hour),
+		Escalation:   model.EscalateSkip,
+	})
+	if code, body := s.do(t, http.MethodPost, "/decline/a/"+id, "a", nil); code != fiber.StatusOK {
+		t.Fatalf("decline: status %v %v", code, body)
+	}
+
+	s.clock.Advance(2 * time.Hour)
+	s.handler.escalateBreached(context.Background())
+
+	task := s.task(t, id)
+	if task.Status != model.Declined {
+		t.Errorf("status %v, want %v", task.Status, model.Declined)
+	}
+	if to := s.mailer.recipients("info"); !reflect.DeepEqual(to, []string{"initiator"}) {
+		t.Errorf("emails to %v, want the initiator", to)
+	}
+}
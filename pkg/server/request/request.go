@@ -1,7 +1,64 @@
 package request
 
+import (
+	"github.com/richard-on/task-service/internal/model"
+	"time"
+)
+
 type AddRequest struct {
-	Name         string   `json:"name"`
-	Description  string   `json:"description,omitempty"`
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description,omitempty"`
+	Coordinators []string               `json:"coordinators,omitempty"`
+	Mode         model.Mode             `json:"mode,omitempty"`
+	Quorum       int                    `json:"quorum,omitempty"`
+	SLA          model.Duration         `json:"sla,omitempty"`
+	Escalation   model.EscalationPolicy `json:"escalation,omitempty"`
+	EscalateTo   string                 `json:"escalate_to,omitempty"`
+	Stages       []StageRequest         `json:"stages,omitempty"`
+	DueAt        *time.Time             `json:"due_at,omitempty"`
+}
+
+// StageRequest describes a single stage of the approval pipeline.
+// If AddRequest has no stages, its Coordinators, Mode, Quorum and escalation settings form the only stage.
+type StageRequest struct {
+	Name         string                 `json:"name,omitempty"`
+	Coordinators []string               `json:"coordinators"`
+	Mode         model.Mode             `json:"mode,omitempty"`
+	Quorum       int                    `json:"quorum,omitempty"`
+	SLA          model.Duration         `json:"sla,omitempty"`
+	Escalation   model.EscalationPolicy `json:"escalation,omitempty"`
+	EscalateTo   string                 `json:"escalate_to,omitempty"`
+}
+
+// ListRequest holds query parameters of the task listing. Times are in RFC 3339 format,
+// Status is a comma separated list and Sort is a field name, prefixed with "-" for descending order.
+type ListRequest struct {
+	Status      string `query:"status"`
+	Coordinator string `query:"coordinator"`
+	CreatedFrom string `query:"created_from"`
+	CreatedTo   string `query:"created_to"`
+	UpdatedFrom string `query:"updated_from"`
+	UpdatedTo   string `query:"updated_to"`
+	Name        string `query:"name"`
+	Sort        string `query:"sort"`
+	Limit       int    `query:"limit"`
+	Cursor      string `query:"cursor"`
+}
+
+// DelegationRequest registers a substitute for the caller. If Start is not set, the delegation starts immediately.
+type DelegationRequest struct {
+	Delegate string     `json:"delegate"`
+	Start    *time.Time `json:"start,omitempty"`
+	End      time.Time  `json:"end"`
+}
+
+// ReassignRequest holds the new coordinators of a stage. If Quorum is set, it replaces the quorum of a Quorum stage.
+type ReassignRequest struct {
 	Coordinators []string `json:"coordinators"`
+	Quorum       int      `json:"quorum,omitempty"`
+}
+
+// DecisionRequest holds an optional comment on an approval or the reason of a refusal.
+type DecisionRequest struct {
+	Comment string `json:"comment,omitempty" form:"comment"`
 }
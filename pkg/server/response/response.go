@@ -3,10 +3,13 @@ package response
 import (
 	"github.com/richard-on/task-service/internal/model"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
 )
 
 type ListResponse struct {
-	Tasks []model.Task
+	Tasks      []model.Task
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int64  `json:"total,omitempty"`
 }
 
 type Info struct {
@@ -19,9 +22,79 @@ type AddResponse struct {
 	Name         string             `json:"name"`
 	Description  string             `json:"description,omitempty"`
 	Coordinators []string           `json:"coordinators"`
+	Stages       []model.Stage      `json:"stages"`
 	Status       model.Status       `json:"status"`
+	DueAt        *time.Time         `json:"due_at,omitempty"`
+	Revision     int                `json:"revision,omitempty"`
 }
 
 type Error struct {
 	Error string `json:"error"`
 }
+
+// Conflict is returned when a task was modified concurrently. Task holds its current state.
+type Conflict struct {
+	Error string     `json:"error"`
+	Task  model.Task `json:"task"`
+}
+
+// History is the audit trail of a task.
+type History struct {
+	ID      primitive.ObjectID `json:"id"`
+	History []model.Event      `json:"history"`
+}
+
+// Verification is the result of checking a task audit trail hash chain.
+// BrokenAt is the index of the first event which failed verification.
+type Verification struct {
+	ID       primitive.ObjectID `json:"id"`
+	Valid    bool               `json:"valid"`
+	Length   int                `json:"length"`
+	BrokenAt *int               `json:"broken_at,omitempty"`
+}
+
+// Receipt is a verifiable record of a coordinator decision. Signature is made over Canonical
+// with the Ed25519 key PublicKey. ContentChanged reports whether task name or description
+// differ from the ones the coordinator decided on.
+type Receipt struct {
+	Statement      model.Statement `json:"statement"`
+	Canonical      string          `json:"canonical"`
+	Signature      string          `json:"signature"`
+	Algorithm      string          `json:"algorithm"`
+	PublicKey      string          `json:"public_key"`
+	Valid          bool            `json:"valid"`
+	ContentChanged bool            `json:"content_changed"`
+}
+
+// Search holds tasks found by full-text search, the most relevant first.
+type Search struct {
+	Results []SearchResult `json:"results"`
+}
+
+// SearchResult is a found task. Highlights hold fragments of the task name and description
+// with matched words wrapped in <em> tags, keyed by field name.
+type SearchResult struct {
+	Task       model.Task        `json:"task"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// Delegations holds delegations made by or to the caller, ordered by start.
+type Delegations struct {
+	Delegations []model.Delegation `json:"delegations"`
+}
+
+// Revisions holds all revisions of a task, the current one last.
+type Revisions struct {
+	ID        primitive.ObjectID `json:"id"`
+	Revision  int                `json:"revision"`
+	Revisions []model.Revision   `json:"revisions"`
+}
+
+// RevisionDiff holds changes made between revisions From and To of a task.
+type RevisionDiff struct {
+	ID      primitive.ObjectID `json:"id"`
+	From    int                `json:"from"`
+	To      int                `json:"to"`
+	Changes []model.Change     `json:"changes"`
+}
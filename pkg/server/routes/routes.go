@@ -3,28 +3,77 @@ package routes
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/richard-on/auth-service/pkg/authService"
+	"github.com/richard-on/task-service/config"
 	"github.com/richard-on/task-service/internal/db"
+	encrypt "github.com/richard-on/task-service/internal/encrypt"
+	"github.com/richard-on/task-service/internal/sign"
 	"github.com/richard-on/task-service/pkg/server/handlers"
 )
 
-func TaskRouter(app fiber.Router, db *db.DB, authClient authService.AuthServiceClient) {
+// TaskRouter registers task endpoints and returns their handler.
+func TaskRouter(app fiber.Router, db db.Store, authClient authService.AuthServiceClient,
+	signer *sign.Signer, keyring *encrypt.Keyring) *handlers.TaskHandler {
 
-	handler := handlers.NewTaskHandler(app, db, authClient)
+	handler := handlers.NewTaskHandler(app, db, authClient, signer, keyring)
+
+	app.Use(handlers.Timeout(config.DbTimeout))
 
 	app.Get("/tasks", handler.List)
 
+	app.Get("/tasks/inbox", handler.Inbox)
+
+	app.Get("/tasks/participated", handler.Participated)
+
+	app.Get("/tasks/search", handler.Search)
+
+	app.Get("/tasks/:task_id/history", handler.History)
+
+	app.Get("/tasks/:task_id/history/verify", handler.VerifyHistory)
+
+	app.Get("/tasks/:task_id/stages/:stage/receipts/:coordinator", handler.Receipt)
+
+	app.Put("/tasks/:task_id/stages/:stage/coordinators", handler.Reassign)
+
 	app.Post("/add", handler.Add)
 
-	app.Delete("/delete/:task_id", handler.Delete)
+	app.Post("/tasks/:task_id/withdraw", handler.Withdraw)
+
+	app.Post("/tasks/:task_id/resubmit", handler.Resubmit)
+
+	app.Get("/tasks/:task_id/revisions", handler.Revisions)
+
+	app.Get("/tasks/:task_id/revisions/diff", handler.RevisionDiff)
+
+	// Deleting a task used to remove it, existing clients withdraw it now.
+	app.Delete("/delete/:task_id", handler.Withdraw)
+
+	app.Delete("/admin/tasks/:task_id", handler.Purge)
 
 	app.Post("/approve/:coordinator/:task_id", handler.Approve)
 
 	app.Post("/decline/:coordinator/:task_id", handler.Decline)
 
+	app.Post("/return/:coordinator/:task_id", handler.Return)
+
+	app.Post("/tasks/:task_id/resume", handler.Resume)
+
+	app.Get("/action", handler.Action)
+
+	app.Post("/action", handler.Action)
+
+	app.Get("/action/decline", handler.DeclineForm)
+
+	app.Post("/delegations", handler.AddDelegation)
+
+	app.Get("/delegations", handler.ListDelegations)
+
+	app.Delete("/delegations/:delegation_id", handler.DeleteDelegation)
+
 	/*app.Post("/approve/:approvalLogin:task_id", handler.Approve)
 
 	app.Post("/tasks/:task_id/decline/:approvalLogin", handler.Decline)
 
 	app.Post("/tasks/run", handler.Run)*/
 
+	return handler
 }
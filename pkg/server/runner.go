@@ -5,9 +5,9 @@ import (
 	"github.com/richard-on/auth-service/pkg/authService"
 	"github.com/richard-on/task-service/config"
 	"github.com/richard-on/task-service/internal/db"
+	encrypt "github.com/richard-on/task-service/internal/encrypt"
+	"github.com/richard-on/task-service/internal/sign"
 	"github.com/richard-on/task-service/pkg/server/routes"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"os"
@@ -46,27 +46,66 @@ func (s *Server) Run() {
 		}
 	}(conn)
 
-	mCtx := context.Background()
+	var signer *sign.Signer
+	if config.SigningKey != "" {
+		signer, err = sign.NewSigner(config.SigningKey)
+		if err != nil {
+			s.log.Fatal(err, "failed to load signing key")
+		}
+	} else if !fiber.IsChild() {
+		s.log.Info("SIGNING_KEY is not set, decisions will not be signed")
+	}
+
+	var keyring *encrypt.Keyring
+	if config.EncryptKeys != "" {
+		keyring, err = encrypt.ParseKeyring(config.EncryptKeys)
+		if err != nil {
+			s.log.Fatal(err, "failed to load encryption keys")
+		}
+	} else if !fiber.IsChild() {
+		s.log.Info("ENCRYPT_KEYS is not set, one-click action links are disabled")
+	}
 
-	mClient, err := mongo.Connect(mCtx,
-		options.Client().ApplyURI(config.DbConnString))
+	var envelope *encrypt.Envelope
+	if config.EncryptAtRest {
+		if keyring == nil {
+			s.log.Fatal(db.ErrNoEnvelope, "ENCRYPT_AT_REST requires ENCRYPT_KEYS")
+		}
+		envelope = encrypt.NewEnvelope(keyring)
+	}
+
+	dbCtx := context.Background()
+
+	taskDb, closeDb, err := db.Open(dbCtx, envelope)
 	if err != nil {
 		s.log.Fatal(err, "failed to connect to database")
 	}
 
-	collection := mClient.Database(config.MongoDbName).Collection(config.MongoCollection)
-
 	defer func() {
-		if err = mClient.Disconnect(mCtx); err != nil {
+		if err = closeDb(); err != nil {
 			s.log.Fatalf(err, "failed to disconnect db")
 		}
 	}()
 
-	taskDb := db.NewDatabase(mCtx, collection)
+	// With prefork the parent migrates before children are started.
+	if migrator, ok := taskDb.(db.Migrator); ok && config.DbMigrate && !fiber.IsChild() {
+		if err = migrator.Migrate(dbCtx); err != nil {
+			s.log.Fatal(err, "failed to migrate database")
+		}
+	}
 
 	// Registering endpoints
 	authClient := authService.NewAuthServiceClient(conn)
-	routes.TaskRouter(v1, taskDb, authClient)
+	handler := routes.TaskRouter(v1, taskDb, authClient, signer, keyring)
+
+	// With prefork only the parent runs the worker.
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	defer stopWorker()
+	if config.WorkerInterval <= 0 {
+		s.log.Info("worker is disabled")
+	} else if !fiber.IsChild() {
+		go handler.RunWorker(workerCtx, config.WorkerInterval)
+	}
 
 	go func() {
 		if err = s.app.Listen(":5000"); err != nil {